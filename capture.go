@@ -4,52 +4,99 @@ Package flowmingo (output Flow Minimizer for Go) provides a way to capture the o
 package flowmingo
 
 import (
-	"bufio"
 	"fmt"
 	"io"
 	"os"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
 // ChunkFromFile represents a chunk of bytes that was captured from an output file.
 type ChunkFromFile struct {
+	// Chunk is the captured data. It's nil if the chunk is stored compressed, use Bytes to get the data.
 	Chunk   []byte
 	OutFile *os.File
+	// Name is the name of the captured target the chunk was written to (see Target).
+	Name string
+	// Time is the moment the chunk was captured.
+	Time time.Time
+
+	// the data of a chunk stored compressed (see Options.Compress)
+	block        *compressedBlock
+	offset, size int
 }
 
 var captureLock sync.Mutex
 
-func pipeReader(rStream io.ReadCloser, outC chan<- *ChunkFromFile, finishCh chan<- bool, outFile *os.File) {
-	reader := bufio.NewReader(rStream)
+const (
+	// defaultPipeCapacity is the capacity of the pipes on most systems.
+	defaultPipeCapacity = 64 << 10
+	// minChunkRead is the minimum space the pipe reader reads into. It's big enough
+	// for the atomic writes (PIPE_BUF) not to be split between chunks, and for the packets of the packet-mode pipes
+	// not to be truncated.
+	minChunkRead = 4 << 10
+	// firstChunkBlockSize is the size of the first block the pipe reader reads into.
+	firstChunkBlockSize = 16 << 10
+)
+
+// pipeReader reads the output from the pipe right into the shared blocks, so every chunk
+// is a slice of a block rather than a separate allocation. The blocks grow from firstChunkBlockSize
+// up to several pipe capacities as the output flows, so small captures stay small while
+// high-volume captures need few allocations and read the whole pipe backlog at once.
+//
+// The blocks are never reused since the captured chunks returned to the caller point into them.
+func pipeReader(outR *os.File, outC chan<- ChunkFromFile, finishCh chan<- bool, target Target, pipeCapacity int,
+	stats *captureStats,
+) {
+	maxBlockSize := 4 * pipeCapacity
+	blockSize := firstChunkBlockSize
+
+	var block []byte
 
 	for {
-		readByte, err := reader.ReadByte()
-		if err != nil {
-			finishCh <- true
+		if len(block) < minChunkRead {
+			block = make([]byte, blockSize)
 
-			break
+			if blockSize < maxBlockSize {
+				blockSize *= 2
+			}
+		}
+
+		n, err := outR.Read(block)
+		if n > 0 {
+			chunk := ChunkFromFile{Chunk: block[:n:n], OutFile: target.File, Name: target.Name, Time: time.Now()}
+			block = block[n:]
+
+			if stats == nil {
+				outC <- chunk
+			} else {
+				// the bytes just read were waiting in the pipe too
+				backlog, measured := pipeBacklog(outR)
+				if measured {
+					backlog += n
+				}
+
+				sendStart := time.Now()
+				outC <- chunk
+				stats.addChunk(n, backlog, time.Since(sendStart))
+			}
 		}
 
-		buffered := reader.Buffered()
-		bytesBlock := make([]byte, 0, buffered+1)
-		bytesBlock = append(bytesBlock, readByte)
+		if err != nil {
+			finishCh <- true
 
-		for ; buffered > 0; buffered = reader.Buffered() {
-			peeked, _ := reader.Peek(buffered)
-			bytesBlock = append(bytesBlock, peeked...)
-			_, _ = reader.Discard(len(peeked))
+			break
 		}
-		outC <- &ChunkFromFile{Chunk: bytesBlock, OutFile: outFile}
 	}
 
-	_ = rStream.Close()
+	_ = outR.Close()
 }
 
 // RestoreFunc is a function that stops capturing, restores the pointers to original output files and returns the captured output.
 // The boolean parameter indicates whether the captured output should be written to the original output files.
-type RestoreFunc func(passThroughOuts bool) []ChunkFromFile
+type RestoreFunc func(passThroughOuts bool) Result
 
 var hookBetweenRestoreCheckAndRestore func()
 
@@ -59,11 +106,14 @@ var hookBetweenRestoreCheckAndRestore func()
 // The list of output files must not be empty, must not contain nil pointers and must not contain duplicates.
 //
 // The output to the given output files is suppressed while capturing.
+// See FileKind for how regular files, pipes, sockets and devices behave while capturing and after restoring.
+// Only the write deadlines set with SetWriteDeadline of this package are carried over to the capture pipe and back,
+// the ones set with (*os.File).SetWriteDeadline directly are not.
 //
 // The output is captured in "chunks from file", where each chunk contains a slice of bytes and the file
 // it was supposed to be written to. The chunks are stored and returned in the order they were captured,
 // not grouped by the file. This order should be very close to the order of the output.
-// The order of the chunks within the same file is guaranteed.
+// The order of the chunks within the same file is guaranteed. See Options.Ordered for the exact order across files.
 //
 // The function returned by Capture can be called with a boolean parameter that indicates whether the captured
 // output should be written to the original output files.
@@ -72,111 +122,497 @@ var hookBetweenRestoreCheckAndRestore func()
 //
 // You can call Capture multiple times to capture the output to multiple files.
 // You can even call Capture with the already captured output files to stack the captures.
-// In this case, the returned "restore" functions should be called in the reverse order of the calls to Capture.
+// The stacked captures can be restored in any order. Restoring a capture that has other captures stacked on top of it
+// re-links the capture above it to the output file below it, so the output passed through by the capture above
+// goes where it would have gone if the captures had been restored in the reverse order. See ActiveCaptures for debugging.
+//
+// The chunks are labeled with the default names of the output files (see Target).
+// Use CaptureTargets to choose the names.
 func Capture(outFiles ...*os.File) RestoreFunc {
 	mustNotBeEmpty(outFiles)
 	mustNotContainNils(outFiles)
 	mustNotContainDuplicates(outFiles)
 
+	return Start(Options{}, defaultTargets(outFiles)...).Restore
+}
+
+// CaptureTargets works like Capture, but takes the output files along with the names
+// the chunks captured from them should be labeled with.
+//
+// Targets with an empty Name get the default name of their files (see Target).
+func CaptureTargets(targets ...Target) RestoreFunc {
+	return Start(Options{}, targets...).Restore
+}
+
+// startCapture starts capturing the targets. If owned is set, the targets have no files, and the capture creates
+// the pipes for them to be given to a child process (see StartCmd) instead of replacing any files.
+func startCapture(opts Options, targets []Target, owned bool) *capture {
 	captureLock.Lock()
 	defer captureLock.Unlock()
 
-	var chunksFromPipesLock sync.RWMutex
+	mustBeSupported(opts)
+
+	outFiles := targetFiles(targets)
+
+	c := &capture{
+		targets:         targets,
+		outFiles:        outFiles,
+		outC:            make(chan ChunkFromFile),
+		finishCh:        make(chan bool, len(outFiles)), // Do not block on external close
+		inFiles:         make([]os.File, len(outFiles)),
+		origOutFiles:    make([]os.File, len(outFiles)),
+		outWFiles:       make([]*os.File, len(outFiles)),
+		outFilesOrigMap: make(map[*os.File]os.File, len(outFiles)),
+		waitCursors:     make(map[string]int),
+		redirections:    make([]*redirection, len(outFiles)),
+		pipeCapacities:  make([]int, len(outFiles)),
+		kinds:           make([]FileKind, len(outFiles)),
+		owned:           owned,
+	}
+
+	if opts.CollectStats {
+		c.stats = &captureStats{}
+	}
 
-	var needPassThrough bool
+	c.filter = opts.Filter
+	c.sink = opts.Sink
+
+	if opts.Compress {
+		c.compression = &compression{}
+	}
 
-	var chunksFromPipes []ChunkFromFile
+	if opts.PassThroughLines != nil {
+		c.passThroughLineRule = opts.PassThroughLines
+		c.lineSegments = make(map[*os.File][]lineSegment, len(targets))
+		c.passedThrough = make(map[int][][2]int)
+	}
 
-	outC := make(chan *ChunkFromFile)
-	finishCh := make(chan bool, len(outFiles)) // Do not block on external close
+	if len(opts.Redactors) > 0 {
+		c.redaction = newRedaction(opts.Redactors, targets)
+	}
 
-	origOutFiles := make([]os.File, len(outFiles))
-	outWFiles := make([]*os.File, len(outFiles))
-	outFilesOrigMap := make(map[*os.File]os.File, len(outFiles))
+	if opts.Ordered {
+		c.newOrderedReceiver()
+	}
 
 	for outFileNumber, outFile := range outFiles {
-		outFile := outFile
+		var outR *os.File
+
+		if !owned {
+			c.kinds[outFileNumber] = detectFileKind(outFile)
+		}
+
+		if c.ordered != nil {
+			c.outWFiles[outFileNumber] = c.newOrderedSender(outFileNumber)
+		} else {
+			outR, c.outWFiles[outFileNumber] = c.newPipe(outFileNumber, opts)
+		}
+
+		switch {
+		case owned:
+			c.ownOutFile(outFileNumber)
+			c.kinds[outFileNumber] = detectFileKind(c.outWFiles[outFileNumber])
+		case opts.RedirectFDs:
+			c.redirectOutFile(outFileNumber)
+		default:
+			replaceOutFile(outFile, c.outWFiles[outFileNumber], &c.origOutFiles[outFileNumber])
+			c.inFiles[outFileNumber] = *c.outWFiles[outFileNumber]
+			c.outFilesOrigMap[outFile] = c.origOutFiles[outFileNumber]
+			applyWriteDeadline(outFile)
+		}
+
+		if outR == nil {
+			continue
+		}
+
+		c.pipeCapacities[outFileNumber], _ = setPipeCapacity(c.outWFiles[outFileNumber], opts.PipeSize)
+
+		readSize := c.pipeCapacities[outFileNumber]
+		if readSize == 0 {
+			readSize = defaultPipeCapacity
+		}
 
-		outR, outW, _ := os.Pipe()
-		outWFiles[outFileNumber] = outW
+		go pipeReader(outR, c.outC, c.finishCh, c.targets[outFileNumber], readSize, c.stats)
+	}
+
+	if c.ordered != nil {
+		go c.ordered.read(c.outC, c.finishCh, c.stats)
+	}
+
+	c.setCrashOutput(opts.CrashOutput)
+	registerCapture(c)
+
+	go c.collect()
+
+	return c
+}
+
+// newPipe creates the pipe to capture the output file to: a blocking one for redirecting the descriptor
+// and a packet-mode one for keeping the write boundaries.
+func (c *capture) newPipe(outFileNumber int, opts Options) (outR, outW *os.File) {
+	var err error
+
+	switch {
+	case opts.WriteBoundaries:
+		outR, outW, _, err = newPacketPipe()
+	case opts.RedirectFDs:
+		outR, outW, _, err = newBlockingPipe()
+	default:
+		outR, outW, err = os.Pipe()
+	}
+
+	if err != nil {
+		panic(fmt.Sprintf("cannot create a pipe for output file %q: %s", c.targets[outFileNumber].Name, err))
+	}
+
+	return outR, outW
+}
 
-		replaceOutFile(outFile, outW, &origOutFiles[outFileNumber])
-		outFilesOrigMap[outFile] = origOutFiles[outFileNumber]
+// newOrderedReceiver creates the socket receiving the output of all the output files of an ordered capture.
+func (c *capture) newOrderedReceiver() {
+	var err error
+
+	c.ordered, err = newOrderedReceiver(c.targets)
+	if err != nil {
+		panic(fmt.Sprintf("cannot create a socket for ordered capturing: %s", err))
+	}
+}
 
-		go pipeReader(outR, outC, finishCh, outFile)
+// newOrderedSender creates the socket to capture the output file to in an ordered capture.
+func (c *capture) newOrderedSender(outFileNumber int) *os.File {
+	outW, err := c.ordered.newSender(outFileNumber)
+	if err != nil {
+		panic(fmt.Sprintf("cannot create a socket for output file %q: %s", c.targets[outFileNumber].Name, err))
 	}
 
-	go func() {
-		for {
-			chunkFromPipe := <-outC
-			if chunkFromPipe == nil {
-				finishCh <- true
+	return outW
+}
+
+// ownOutFile makes the write end of the capture pipe the output file of the target.
+// The output captured from the targets named StdoutName and StderrName is passed through to os.Stdout
+// and os.Stderr, the output of the other targets is not passed through.
+func (c *capture) ownOutFile(outFileNumber int) {
+	outW := c.outWFiles[outFileNumber]
+
+	c.targets[outFileNumber].File = outW
+	c.outFiles[outFileNumber] = outW
+	c.inFiles[outFileNumber] = *outW
+	c.origOutFiles[outFileNumber] = *outW
+
+	switch c.targets[outFileNumber].Name {
+	case StdoutName:
+		c.outFilesOrigMap[outW] = *os.Stdout
+	case StderrName:
+		c.outFilesOrigMap[outW] = *os.Stderr
+	}
+}
 
-				return
+// redirectOutFile redirects the file descriptor of the output file to the write end of its capture pipe.
+// The pointer to the output file stays intact.
+func (c *capture) redirectOutFile(outFileNumber int) {
+	outFile := c.outFiles[outFileNumber]
+	outW := c.outWFiles[outFileNumber]
+
+	fd, err := fileFd(outFile)
+	if err != nil {
+		panic(fmt.Sprintf("cannot get the file descriptor of output file %q: %s", c.targets[outFileNumber].Name, err))
+	}
+
+	savedFd, err := redirectFd(fd, int(outW.Fd()))
+	if err != nil {
+		panic(fmt.Sprintf("cannot redirect the file descriptor of output file %q: %s", c.targets[outFileNumber].Name, err))
+	}
+
+	savedFile := os.NewFile(uintptr(savedFd), outFile.Name())
+
+	c.redirections[outFileNumber] = &redirection{fd: fd, savedFd: savedFd, savedFile: savedFile}
+	c.inFiles[outFileNumber] = *outFile
+	c.origOutFiles[outFileNumber] = *outFile
+	c.outFilesOrigMap[outFile] = *savedFile
+}
+
+// redirection is the state of a file descriptor redirected to a pipe.
+type redirection struct {
+	fd        int      // the redirected file descriptor
+	savedFd   int      // the duplicate of the original file descriptor
+	savedFile *os.File // the file owning savedFd
+}
+
+// capture is the state of a single call to Capture.
+type capture struct {
+	id          int64
+	createdAt   string
+	stack       []byte
+	targets     []Target
+	leakHandler func(info CaptureInfo) // the leak handler at the moment of starting
+
+	outFiles        []*os.File
+	outC            chan ChunkFromFile // the zero chunk signals the end
+	finishCh        chan bool
+	inFiles         []os.File // the contents of the output files while capturing
+	origOutFiles    []os.File // the contents of the output files to restore
+	outWFiles       []*os.File
+	outFilesOrigMap map[*os.File]os.File // where the output is passed through to
+	redirections    []*redirection       // nil for the output files whose descriptors are not redirected
+	pipeCapacities  []int                // zero if unknown
+	kinds           []FileKind           // the kinds of the original output files
+	owned           bool                 // the output files are the capture pipes given to a command
+	stats           *captureStats        // nil unless collecting stats
+	ordered         *orderedReceiver     // nil unless capturing in the strict order
+	redaction       *redaction           // nil unless redacting
+	filter          func(chunk ChunkFromFile) bool
+	sink            Sink         // nil unless the chunks are not kept in memory
+	compression     *compression // nil unless the chunks are stored compressed
+
+	writersLock   sync.RWMutex // guards sending to outC by the writers bypassing the pipes
+	writersClosed bool
+
+	ownsCrashOutput bool
+
+	chunksFromPipesLock sync.RWMutex
+	needPassThrough     bool
+	passThroughTo       map[*os.File]io.Writer              // where the output is passed through to while restoring
+	chunksNotify        chan struct{}                       // closed on storing a chunk if someone waits for the output
+	waitCursors         map[string]int                      // the output consumed by WaitFor (by the target name)
+	collected           bool                                // all the output is stored
+	passThroughLineRule func(name string, line []byte) bool // nil unless passing through lines selectively
+	lineSegments        map[*os.File][]lineSegment          // the incomplete lines waiting for the rest
+	passedThrough       map[int][][2]int                    // the parts of the chunks passed through (by chunk index)
+	chunksFromPipes     []ChunkFromFile
+}
+
+func (c *capture) collect() {
+	for {
+		chunkFromPipe := <-c.outC
+		if chunkFromPipe.OutFile == nil {
+			if c.redaction != nil {
+				for _, chunk := range c.redaction.flush() {
+					c.store(chunk)
+				}
+			}
+
+			c.chunksFromPipesLock.Lock()
+			if c.passThroughLineRule != nil && !c.needPassThrough {
+				c.passThroughIncompleteLines()
 			}
 
-			chunksFromPipesLock.Lock()
-			chunksFromPipes = append(chunksFromPipes, *chunkFromPipe)
-
-			// Pass the chunk to the original output files for the case
-			// when the restore function is called with passThroughOuts=true,
-			// and it has already flushed all the previous chunks,
-			// but hasn't closed the outWFiles yet.
-			//
-			// Since there is a tiny time window between restoring the out files and closing the outWFiles,
-			// there can be goroutines that have already started writing to the restored out files concurrently.
-			// This means that the chunks that were captured after the restore function was called
-			// can be written to the original output files after more recent concurrent writes.
-			// Note: it's only related to the writes happening after the restore function restored the out files and
-			// before the restore function closed outWFiles.
-			//
-			// Anyway, remember that FlowMinGo is not thread-safe for now because it doesn't acquire the write lock
-			// on os.File upon replacing. Strange things may happen on concurrent writes at moments of replacing/restoring.
-			if needPassThrough {
-				origOutPipe := outFilesOrigMap[chunkFromPipe.OutFile]
-				_, _ = origOutPipe.Write(chunkFromPipe.Chunk)
+			if c.compression != nil {
+				c.compression.seal()
 			}
-			chunksFromPipesLock.Unlock()
+
+			c.collected = true
+			c.notifyChunks()
+			c.chunksFromPipesLock.Unlock()
+
+			c.finishCh <- true
+
+			return
 		}
-	}()
 
-	return func(passThroughOuts bool) []ChunkFromFile {
-		captureLock.Lock()
-		defer captureLock.Unlock()
+		if c.redaction != nil {
+			chunkFromPipe = c.redaction.redact(chunkFromPipe)
+		}
 
-		if outC == nil {
-			panic(fmt.Sprintf("Capture function was already called for output files %v\n", origOutFiles))
+		c.store(chunkFromPipe)
+	}
+}
+
+// store stores the chunk unless it's empty or filtered out.
+func (c *capture) store(chunkFromPipe ChunkFromFile) {
+	if len(chunkFromPipe.Chunk) == 0 || c.filter != nil && !c.filter(chunkFromPipe) {
+		return
+	}
+
+	c.chunksFromPipesLock.Lock()
+	switch {
+	case c.sink != nil:
+		c.sink.WriteChunk(chunkFromPipe)
+	case c.compression != nil:
+		c.chunksFromPipes = append(c.chunksFromPipes, c.compression.compress(chunkFromPipe))
+	default:
+		c.chunksFromPipes = append(c.chunksFromPipes, chunkFromPipe)
+	}
+
+	c.notifyChunks()
+
+	// Pass the chunk to the original output files for the case
+	// when the restore function is called with passThroughOuts=true,
+	// and it has already flushed all the previous chunks,
+	// but hasn't closed the outWFiles yet.
+	//
+	// Since there is a tiny time window between restoring the out files and closing the outWFiles,
+	// there can be goroutines that have already started writing to the restored out files concurrently.
+	// This means that the chunks that were captured after the restore function was called
+	// can be written to the original output files after more recent concurrent writes.
+	// Note: it's only related to the writes happening after the restore function restored the out files and
+	// before the restore function closed outWFiles.
+	//
+	// Anyway, remember that FlowMinGo is not thread-safe for now because it doesn't acquire the write lock
+	// on os.File upon replacing. Strange things may happen on concurrent writes at moments of replacing/restoring.
+	if c.needPassThrough {
+		if dest := c.passThroughTo[chunkFromPipe.OutFile]; dest != nil {
+			_, _ = dest.Write(chunkFromPipe.Chunk)
 		}
+	} else if c.passThroughLineRule != nil {
+		c.passThroughLines(len(c.chunksFromPipes)-1, chunkFromPipe)
+	}
+	c.chunksFromPipesLock.Unlock()
+}
+
+// restore stops capturing and restores the output files. The captured output is passed through
+// if passThroughOuts is set or if passThroughIf (when not nil) returns true for the whole captured output.
+// The output is passed through to the writers from dests by the names of the targets (dropping the output
+// of the targets missing there) if dests is not nil, and to the original output files otherwise.
+func (c *capture) restore(passThroughOuts bool, passThroughIf func(Result) bool, dests map[string]io.Writer) Result {
+	captureLock.Lock()
+	defer captureLock.Unlock()
+
+	if c.outC == nil {
+		panic(fmt.Sprintf("Capture function was already called for output files %v\n", c.origOutFiles))
+	}
+
+	mustBeRelinkable(c)
+
+	// flush the already captured chunks to the original output files before restoring out files
+	if passThroughOuts {
+		c.chunksFromPipesLock.Lock()
+		c.passThroughTo = c.passThroughWriters(dests)
+		flushChunksToOrigPipes(c.chunksFromPipes, c.alreadyPassedThrough(dests), c.passThroughTo)
+
+		c.needPassThrough = true
+		c.chunksFromPipesLock.Unlock()
+	}
+
+	closed := c.closedOutFiles()
+
+	c.restoreOrRelinkOutFiles(closed)
+	c.restoreCrashOutput()
+	unregisterCapture(c)
 
-		// flush the already captured chunks to the original output files before restoring out files
-		if passThroughOuts {
-			chunksFromPipesLock.RLock()
-			flushChunksToOrigPipes(chunksFromPipes, outFilesOrigMap)
+	for _, outW := range c.outWFiles {
+		_ = outW.Close()
 
-			needPassThrough = true
-			chunksFromPipesLock.RUnlock()
+		if c.ordered == nil {
+			<-c.finishCh // wait for the out pipe reader to finish
 		}
+	}
+
+	if c.ordered != nil {
+		c.ordered.stop()
+
+		<-c.finishCh // wait for the ordered receiver to finish
+	}
+
+	// wait for the writers bypassing the pipes to finish and stop accepting new writes
+	c.writersLock.Lock()
+	c.writersClosed = true
+	c.writersLock.Unlock()
 
-		restoreOutFiles(outFiles, outWFiles, origOutFiles)
+	c.outC <- ChunkFromFile{} // for old Golang versions
 
-		for _, outW := range outWFiles {
-			_ = outW.Close()
+	<-c.finishCh // wait for the outC reader to finish
+	close(c.outC)
+	c.outC = nil
 
-			<-finishCh // wait for the out pipe reader to finish
+	if c.sink != nil {
+		c.sink.Flush()
+	}
+
+	if !passThroughOuts && passThroughIf != nil && passThroughIf(Result(c.chunksFromPipes)) {
+		flushChunksToOrigPipes(c.chunksFromPipes, c.alreadyPassedThrough(dests), c.passThroughWriters(dests))
+	}
+
+	// The duplicates of the redirected descriptors are closed only now,
+	// since the output is passed through to them until the outC reader finishes
+	for _, redirection := range c.redirections {
+		if redirection != nil {
+			_ = redirection.savedFile.Close()
 		}
+	}
 
-		outC <- nil // for old Golang versions
+	// The files closed while capturing stay closed after restoring
+	for outFileNumber, isClosed := range closed {
+		if isClosed && c.redirections[outFileNumber] == nil {
+			origOutFile := c.origOutFiles[outFileNumber]
+			_ = origOutFile.Close()
+		}
+	}
 
-		<-finishCh // wait for the outC reader to finish
-		close(outC)
-		outC = nil
+	close(c.finishCh)
 
-		close(finishCh)
+	return Result(c.chunksFromPipes)
+}
+
+// restoreOrRelinkOutFiles restores the output files the capture is on the top of the stacks of,
+// and re-links the captures stacked on top of the capture to the original output files of the capture.
+// The redirected descriptors closed while capturing are not restored, since their numbers may be reused already.
+func (c *capture) restoreOrRelinkOutFiles(closed []bool) {
+	var (
+		names        []string
+		outFiles     []*os.File
+		inFiles      []os.File
+		origOutFiles []os.File
+		aboveOfFiles []*capture
+	)
+
+	for outFileNumber, outFile := range c.outFiles {
+		if above := captureAbove(c, outFile); above != nil {
+			aboveOfFiles = append(aboveOfFiles, above)
+
+			continue
+		}
 
-		return chunksFromPipes
+		aboveOfFiles = append(aboveOfFiles, nil)
+		names = append(names, c.targets[outFileNumber].Name)
+		outFiles = append(outFiles, outFile)
+		inFiles = append(inFiles, c.inFiles[outFileNumber])
+		origOutFiles = append(origOutFiles, c.origOutFiles[outFileNumber])
+	}
+
+	restoreOutFiles(fmt.Sprintf("capture #%d (created at %s)", c.id, c.createdAt), names, outFiles, inFiles, origOutFiles)
+
+	// the deadlines set while capturing went to the pipes
+	for _, outFile := range outFiles {
+		applyWriteDeadline(outFile)
+	}
+
+	for outFileNumber, redirection := range c.redirections {
+		if redirection == nil || closed[outFileNumber] {
+			continue
+		}
+
+		if err := restoreFd(redirection.fd, redirection.savedFd); err != nil {
+			panic(fmt.Sprintf("cannot restore the file descriptor of output file %q: %s", c.targets[outFileNumber].Name, err))
+		}
+	}
+
+	for outFileNumber, above := range aboveOfFiles {
+		if above != nil {
+			above.relinkOutFile(c.outFiles[outFileNumber], c.origOutFiles[outFileNumber])
+		}
 	}
 }
 
+func (c *capture) fileNumber(outFile *os.File) int {
+	for outFileNumber := range c.outFiles {
+		if c.outFiles[outFileNumber] == outFile {
+			return outFileNumber
+		}
+	}
+
+	return -1
+}
+
+// relinkOutFile makes the capture restore the output file to the given original output file
+// and pass the captured output through to it. It's called when the capture below is restored.
+func (c *capture) relinkOutFile(outFile *os.File, origOutFile os.File) {
+	c.chunksFromPipesLock.Lock()
+	defer c.chunksFromPipesLock.Unlock()
+
+	c.origOutFiles[c.fileNumber(outFile)] = origOutFile
+	c.outFilesOrigMap[outFile] = origOutFile
+}
+
 func mustNotContainDuplicates(outFiles []*os.File) {
 	outFilesMap := make(map[*os.File]struct{}, len(outFiles))
 	for _, outFile := range outFiles {
@@ -211,13 +647,15 @@ func replaceOutFile(outFile, outW, origOutFileToStore *os.File) {
 	*origOutFileToStore = *(*os.File)(unsafe.Pointer(&origOutFile))
 }
 
-func restoreOutFiles(outFiles, outWFiles []*os.File, origOutFiles []os.File) {
+// restoreOutFiles restores the contents of the output files. The owner and the names of the files
+// describe what is restored in the panic messages.
+func restoreOutFiles(owner string, names []string, outFiles []*os.File, inFiles, origOutFiles []os.File) {
 	for i, outFile := range outFiles {
 		//nolint:gosec // *outFile
 		loadedOutFile := atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(outFile)))
-		//nolint:gosec // *outFile != *outWFiles[i]
-		if loadedOutFile != *(*unsafe.Pointer)(unsafe.Pointer(outWFiles[i])) {
-			panic(fmt.Sprintf("cannot restore because original out file #%d was changed from the outside", i))
+		//nolint:gosec // *outFile != inFiles[i]
+		if loadedOutFile != *(*unsafe.Pointer)(unsafe.Pointer(&inFiles[i])) {
+			panic(changedFromOutsideMessage(owner, names[i]))
 		}
 	}
 
@@ -235,18 +673,69 @@ func restoreOutFiles(outFiles, outWFiles []*os.File, origOutFiles []os.File) {
 		//nolint:gosec // *outFile = origOutFiles[outFileNumber]
 		if !atomic.CompareAndSwapPointer(
 			(*unsafe.Pointer)(unsafe.Pointer(outFile)),
-			*(*unsafe.Pointer)(unsafe.Pointer(outWFiles[outFileNumber])),
+			*(*unsafe.Pointer)(unsafe.Pointer(&inFiles[outFileNumber])),
 			*(*unsafe.Pointer)(unsafe.Pointer(&origOutFiles[outFileNumber]))) {
 			// Highly unlikely case
-			panic(fmt.Sprintf("cannot restore because original out file #%d was changed from the outside", outFileNumber))
+			panic(changedFromOutsideMessage(owner, names[outFileNumber]))
+		}
+	}
+}
+
+func changedFromOutsideMessage(owner, name string) string {
+	return fmt.Sprintf("cannot restore %s: file %q was changed from the outside while capturing "+
+		"(e.g. by assigning to *os.Stdout)", owner, name)
+}
+
+// passThroughWriters returns the writers to pass the output of the output files through to:
+// the writers from dests by the names of the targets if dests is not nil, and the original output files otherwise.
+func (c *capture) passThroughWriters(dests map[string]io.Writer) map[*os.File]io.Writer {
+	writers := make(map[*os.File]io.Writer, len(c.targets))
+
+	for _, target := range c.targets {
+		if dests != nil {
+			if dest, ok := dests[target.Name]; ok && dest != nil {
+				writers[target.File] = dest
+			}
+
+			continue
+		}
+
+		if origOutPipe, ok := c.outFilesOrigMap[target.File]; ok {
+			writers[target.File] = &origOutPipe
 		}
 	}
+
+	return writers
 }
 
-func flushChunksToOrigPipes(chunks []ChunkFromFile, outFilesOrigMap map[*os.File]os.File) {
-	for _, chunk := range chunks {
-		origOutPipe := outFilesOrigMap[chunk.OutFile]
-		_, _ = origOutPipe.Write(chunk.Chunk)
+// alreadyPassedThrough returns the parts of the chunks the writers for dests already got:
+// the lines passed through by PassThroughLines went to the original output files, not to dests.
+func (c *capture) alreadyPassedThrough(dests map[string]io.Writer) map[int][][2]int {
+	if dests != nil {
+		return nil
+	}
+
+	return c.passedThrough
+}
+
+// flushChunksToOrigPipes writes the chunks to the writers of their output files skipping the parts
+// that have already been passed through (by the index of the chunk).
+func flushChunksToOrigPipes(chunks []ChunkFromFile, passedThrough map[int][][2]int, writers map[*os.File]io.Writer) {
+	var cache blockCache
+
+	for chunkIndex, chunk := range chunks {
+		dest := writers[chunk.OutFile]
+		if dest == nil {
+			continue
+		}
+
+		if passed, ok := passedThrough[chunkIndex]; ok {
+			writeNotPassedThrough(dest, cache.bytes(chunk), passed)
+
+			continue
+		}
+
+		_, _ = dest.Write(cache.bytes(chunk))
 	}
 }
 
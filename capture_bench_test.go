@@ -0,0 +1,169 @@
+package flowmingo_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func openDevNull(b *testing.B) *os.File {
+	b.Helper()
+
+	outFile, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return outFile
+}
+
+// discard drops all the captured chunks, so the benchmarks measure the capture path
+// rather than keeping all the output in memory until restoring.
+func discard(flowmingo.ChunkFromFile) bool {
+	return false
+}
+
+func benchmarkCaptureWrites(b *testing.B, writeSize int) {
+	outFile := openDevNull(b)
+	defer func() { _ = outFile.Close() }()
+
+	data := bytes.Repeat([]byte{'x'}, writeSize)
+
+	b.SetBytes(int64(writeSize))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	restore := flowmingo.Start(flowmingo.Options{Filter: discard}, flowmingo.Target{File: outFile}).Restore
+
+	for i := 0; i < b.N; i++ {
+		_, _ = outFile.Write(data)
+	}
+
+	restore(false)
+}
+
+// BenchmarkCapture_SmallWrites measures the latency of small writes to a captured file.
+func BenchmarkCapture_SmallWrites(b *testing.B) {
+	benchmarkCaptureWrites(b, 64)
+}
+
+// BenchmarkCapture_LargeWrites measures the throughput of large writes to a captured file.
+func BenchmarkCapture_LargeWrites(b *testing.B) {
+	benchmarkCaptureWrites(b, 64<<10)
+}
+
+// BenchmarkCapture_ConcurrentWriters measures the throughput of many goroutines writing to a captured file.
+func BenchmarkCapture_ConcurrentWriters(b *testing.B) {
+	outFile := openDevNull(b)
+	defer func() { _ = outFile.Close() }()
+
+	data := bytes.Repeat([]byte{'x'}, 256)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.SetParallelism(8)
+	b.ResetTimer()
+
+	restore := flowmingo.Start(flowmingo.Options{Filter: discard}, flowmingo.Target{File: outFile}).Restore
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = outFile.Write(data)
+		}
+	})
+
+	restore(false)
+}
+
+// BenchmarkCapture_MultipleFiles measures the throughput of writes to several files captured together.
+func BenchmarkCapture_MultipleFiles(b *testing.B) {
+	outFiles := []*os.File{openDevNull(b), openDevNull(b), openDevNull(b)}
+
+	defer func() {
+		for _, outFile := range outFiles {
+			_ = outFile.Close()
+		}
+	}()
+
+	data := bytes.Repeat([]byte{'x'}, 1<<10)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	restore := flowmingo.Start(flowmingo.Options{Filter: discard},
+		flowmingo.Target{File: outFiles[0], Name: "0"},
+		flowmingo.Target{File: outFiles[1], Name: "1"},
+		flowmingo.Target{File: outFiles[2], Name: "2"},
+	).Restore
+
+	for i := 0; i < b.N; i++ {
+		_, _ = outFiles[i%len(outFiles)].Write(data)
+	}
+
+	restore(false)
+}
+
+// BenchmarkCapture_Stacked measures the throughput of writes to a file captured by several stacked captures
+// including passing the output through all of them on restore. The captures are restarted every 1024 writes,
+// so the output kept by the captures above the bottom one doesn't pile up.
+func BenchmarkCapture_Stacked(b *testing.B) {
+	const writesPerCapture = 1024
+
+	outFile := openDevNull(b)
+	defer func() { _ = outFile.Close() }()
+
+	data := bytes.Repeat([]byte{'x'}, 1<<10)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i += writesPerCapture {
+		restore1 := flowmingo.Start(flowmingo.Options{Filter: discard}, flowmingo.Target{File: outFile}).Restore
+		restore2 := flowmingo.Capture(outFile)
+		restore3 := flowmingo.Capture(outFile)
+
+		for j := i; j < b.N && j < i+writesPerCapture; j++ {
+			_, _ = outFile.Write(data)
+		}
+
+		restore3(true)
+		restore2(true)
+		restore1(false)
+	}
+}
+
+// BenchmarkCapture_StartRestore measures the overhead of starting and restoring a capture.
+func BenchmarkCapture_StartRestore(b *testing.B) {
+	outFile := openDevNull(b)
+	defer func() { _ = outFile.Close() }()
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		flowmingo.Capture(outFile)(false)
+	}
+}
+
+// BenchmarkCapture_SmallWritesWithStats measures the overhead of collecting the runtime statistics.
+func BenchmarkCapture_SmallWritesWithStats(b *testing.B) {
+	outFile := openDevNull(b)
+	defer func() { _ = outFile.Close() }()
+
+	data := bytes.Repeat([]byte{'x'}, 64)
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	capturing := flowmingo.Start(flowmingo.Options{CollectStats: true, Filter: discard}, flowmingo.Target{File: outFile})
+
+	for i := 0; i < b.N; i++ {
+		_, _ = outFile.Write(data)
+	}
+
+	capturing.Restore(false)
+}
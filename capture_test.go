@@ -2,8 +2,10 @@ package flowmingo_test
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -120,13 +122,73 @@ func TestRestoreFunc_DoesntAllowToBeCalledTwice(t *testing.T) {
 }
 
 func TestRestoreFunc_ChecksIfOutputIsReplaced(t *testing.T) {
-	restoreFunc1 := flowmingo.CaptureStdoutAndStderr()
-	restoreFunc2 := flowmingo.Capture(os.Stderr)
+	restoreFunc := flowmingo.Capture(os.Stdout)
+	capturedStdout := *os.Stdout
+
+	reader, writer, err := os.Pipe()
+	assertNoError(t, err)
+
+	defer func() { _ = reader.Close() }()
+	defer func() { _ = writer.Close() }()
+
+	*os.Stdout = *writer
+
+	assertPanics(t, func() { restoreFunc(true) })
+
+	*os.Stdout = capturedStdout
+	restoreFunc(true)
+}
+
+func TestRestoreFunc_NamesFileReplacedFromOutside(t *testing.T) {
+	restoreFunc := flowmingo.Capture(os.Stdout, os.Stderr)
+	restoreAbove := flowmingo.Capture(os.Stdout)
+	capturedStderr := *os.Stderr
+
+	reader, writer, err := os.Pipe()
+	assertNoError(t, err)
 
-	assertPanics(t, func() { restoreFunc1(true) })
+	defer func() { _ = reader.Close() }()
+	defer func() { _ = writer.Close() }()
 
-	restoreFunc2(false)
-	restoreFunc1(true)
+	*os.Stderr = *writer
+
+	// only stderr is restored here, stdout is re-linked to the capture above
+	message := panicMessage(func() { restoreFunc(false) })
+	assertTrue(t, strings.Contains(message, `file "stderr" was changed`), "unexpected panic: "+message)
+	assertTrue(t, strings.Contains(message, "capture #"), "unexpected panic: "+message)
+
+	*os.Stderr = capturedStderr
+	restoreFunc(false)
+	restoreAbove(false)
+}
+
+func TestRestoreFunc_AllowsOutOfOrderRestore(t *testing.T) {
+	origStdout := os.Stdout
+
+	defer func() { os.Stdout = origStdout }()
+
+	outR, outW, err := os.Pipe()
+	assertNoError(t, err)
+	os.Stdout = outW
+
+	restore1 := flowmingo.Capture(os.Stdout)
+	_, _ = os.Stdout.WriteString("1")
+	restore2 := flowmingo.Capture(os.Stdout)
+	_, _ = os.Stdout.WriteString("2")
+
+	result1 := restore1(true)
+	_, _ = os.Stdout.WriteString("3")
+	result2 := restore2(true)
+	_, _ = os.Stdout.WriteString("4")
+
+	assertEqualStrings(t, "1", result1.Stdout())
+	assertEqualStrings(t, "23", result2.Stdout())
+
+	_ = outW.Close()
+	var outBuf bytes.Buffer
+	_, err = io.Copy(&outBuf, outR)
+	assertNoError(t, err)
+	assertEqualStrings(t, "1234", outBuf.String())
 }
 
 func assertEqualStrings(t *testing.T, expected, actual string) {
@@ -167,6 +229,15 @@ func assertNoError(t *testing.T, err error) {
 	}
 }
 
+func panicMessage(funcToCall func()) (message string) {
+	defer func() {
+		message = fmt.Sprint(recover())
+	}()
+	funcToCall()
+
+	return ""
+}
+
 func assertPanics(t *testing.T, funcToCall func()) {
 	t.Helper()
 
@@ -0,0 +1,67 @@
+package flowmingo
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// CaptureCmd prepares the command for capturing its STDOUT, STDERR and extra descriptors
+// and returns a function for stopping capturing and getting the captured output.
+// See StartCmd for more information.
+func CaptureCmd(cmd *exec.Cmd, extraNames ...string) RestoreFunc {
+	return StartCmd(Options{}, cmd, extraNames...).Restore
+}
+
+// StartCmd prepares the command for capturing its output according to the options
+// and returns the capture. The command is to be started by the caller.
+//
+// The command gets the write ends of the capture pipes as its STDOUT and STDERR labeled with StdoutName
+// and StderrName, and as the extra files labeled with extraNames. The extra files are appended to cmd.ExtraFiles
+// in the order of the names, so without other extra files the first name labels the descriptor 3 of the command,
+// the second one labels the descriptor 4 and so on. The command writes right to the capture pipes,
+// so no output files are replaced, and the ordering is the same as with Capture (see Options.Ordered
+// for the exact order).
+//
+// The output passed through goes to os.Stdout and os.Stderr of the process,
+// the output of the extra descriptors is not passed through.
+//
+// Restore the capture after the command finishes, since restoring waits for the command to close
+// the descriptors. StartCmd panics if cmd.Stdout or cmd.Stderr is already set, if the names are empty
+// or duplicated, or if opts.RedirectFDs is set.
+func StartCmd(opts Options, cmd *exec.Cmd, extraNames ...string) *Capturing {
+	if cmd.Stdout != nil || cmd.Stderr != nil {
+		panic("the output of the command is already set")
+	}
+
+	if opts.RedirectFDs {
+		panic("RedirectFDs can't be used for capturing a command")
+	}
+
+	targets := make([]Target, 0, 2+len(extraNames))
+	targets = append(targets, Target{Name: StdoutName}, Target{Name: StderrName})
+
+	names := map[string]bool{StdoutName: true, StderrName: true}
+
+	for _, name := range extraNames {
+		if name == "" {
+			panic("the name of an extra file is empty")
+		}
+
+		if names[name] {
+			panic(fmt.Sprintf("the name %q is duplicated", name))
+		}
+
+		names[name] = true
+		targets = append(targets, Target{Name: name})
+	}
+
+	mustBeConsistent(opts)
+
+	c := startCapture(opts, targets, true)
+
+	cmd.Stdout = c.outWFiles[0]
+	cmd.Stderr = c.outWFiles[1]
+	cmd.ExtraFiles = append(cmd.ExtraFiles, c.outWFiles[2:]...)
+
+	return watchForLeak(c)
+}
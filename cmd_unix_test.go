@@ -0,0 +1,101 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package flowmingo_test
+
+import (
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestStartCmd(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo out; echo err >&2; echo diag >&3; echo trace >&4")
+	capturing := flowmingo.StartCmd(flowmingo.Options{}, cmd, "diag", "trace")
+
+	assertNoError(t, cmd.Run())
+
+	result := capturing.Restore(false)
+
+	assertEqualStrings(t, "out\n", result.Stdout())
+	assertEqualStrings(t, "err\n", result.Stderr())
+	assertEqualStrings(t, "diag\n", string(result.ByName("diag").Combined()))
+	assertEqualStrings(t, "trace\n", string(result.ByName("trace").Combined()))
+}
+
+func TestCaptureCmd_PassesThroughStdoutAndStderr(t *testing.T) {
+	outer := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout}, flowmingo.Target{File: os.Stderr})
+
+	cmd := exec.Command("sh", "-c", "echo out; echo err >&2; echo diag >&3")
+	restore := flowmingo.CaptureCmd(cmd, "diag")
+	assertNoError(t, cmd.Run())
+	restore(true)
+
+	outerResult := outer.Restore(false)
+
+	assertEqualStrings(t, "out\n", outerResult.Stdout())
+	assertEqualStrings(t, "err\n", outerResult.Stderr())
+}
+
+func TestStartCmd_PanicsOnInvalidArguments(t *testing.T) {
+	assertPanics(t, func() {
+		cmd := exec.Command("true")
+		cmd.Stdout = os.Stdout
+		flowmingo.StartCmd(flowmingo.Options{}, cmd)
+	})
+	assertPanics(t, func() { flowmingo.StartCmd(flowmingo.Options{}, exec.Command("true"), "diag", "diag") })
+	assertPanics(t, func() { flowmingo.StartCmd(flowmingo.Options{}, exec.Command("true"), flowmingo.StdoutName) })
+	assertPanics(t, func() { flowmingo.StartCmd(flowmingo.Options{RedirectFDs: true}, exec.Command("true")) })
+}
+
+func TestCmdSession(t *testing.T) {
+	session, err := flowmingo.NewCmdSession(exec.Command("sh", "-c", `printf "Name: "; read name; echo "Hello, $name"; cat`))
+	assertNoError(t, err)
+
+	defer session.Close()
+
+	_ = session.Expect(regexp.MustCompile(`Name: $`))
+	_ = session.Send("gopher\n")
+	_ = session.Expect(regexp.MustCompile(`Hello, gopher\n`))
+	_ = session.Send("echo\n")
+	_ = session.SendEOF()
+	assertNoError(t, session.ExpectEOF())
+
+	assertEqualStrings(t, "< Name: \n> gopher\n< Hello, gopher\n> echo\n> EOF\n< echo\nEOF\n", session.Transcript())
+}
+
+func TestCmdSession_FailsOnNonZeroExitWithStderr(t *testing.T) {
+	session, err := flowmingo.NewCmdSession(exec.Command("sh", "-c", `echo oops >&2; exit 3`))
+	assertNoError(t, err)
+
+	defer session.Close()
+
+	err = session.ExpectEOF()
+
+	sessionErr, ok := err.(*flowmingo.SessionError)
+	assertTrue(t, ok, "Expected a *SessionError")
+
+	_, ok = sessionErr.Err.(*exec.ExitError)
+	assertTrue(t, ok, "Expected an *exec.ExitError")
+	assertEqualStrings(t, "<2 oops\n!! exit status 3\n", sessionErr.Transcript)
+}
+
+func TestCmdSession_SendTimesOut(t *testing.T) {
+	session, err := flowmingo.NewCmdSession(exec.Command("sleep", "10"))
+	assertNoError(t, err)
+
+	session.Timeout = 100 * time.Millisecond
+
+	defer session.Close()
+
+	err = session.Send(strings.Repeat("x", 1<<20))
+
+	sessionErr, ok := err.(*flowmingo.SessionError)
+	assertTrue(t, ok, "Expected a *SessionError")
+	assertEqualStrings(t, "expected the program to read the input within 100ms", sessionErr.Err.Error())
+}
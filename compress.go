@@ -0,0 +1,107 @@
+package flowmingo
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+)
+
+// compressedBlockSize is the size of the output compressed together. Compressing the chunks one by one
+// would compress them poorly since the chunks are often small.
+const compressedBlockSize = 256 << 10
+
+// compressedBlock is a block of the captured output shared by the chunks it contains.
+// The block is kept uncompressed while it's filled and is compressed with flate when it's sealed.
+type compressedBlock struct {
+	raw        []byte // nil once sealed
+	compressed []byte
+	size       int
+}
+
+func (b *compressedBlock) seal() {
+	var buf bytes.Buffer
+
+	writer, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	_, _ = writer.Write(b.raw)
+	_ = writer.Close()
+
+	b.compressed, b.size, b.raw = buf.Bytes(), len(b.raw), nil
+}
+
+// bytes returns the uncompressed contents of the block. It decompresses a sealed block every time.
+func (b *compressedBlock) bytes() []byte {
+	if b.raw != nil {
+		return b.raw
+	}
+
+	data := make([]byte, b.size)
+	_, _ = io.ReadFull(flate.NewReader(bytes.NewReader(b.compressed)), data)
+
+	return data
+}
+
+// compression puts the captured chunks into the compressed blocks (see Options.Compress).
+type compression struct {
+	block *compressedBlock
+}
+
+// compress moves the data of the chunk into the current block and returns the chunk referring to the block.
+func (c *compression) compress(chunk ChunkFromFile) ChunkFromFile {
+	if c.block == nil || len(c.block.raw) >= compressedBlockSize {
+		c.seal()
+		c.block = &compressedBlock{raw: make([]byte, 0, compressedBlockSize)}
+	}
+
+	chunk.block, chunk.offset, chunk.size = c.block, len(c.block.raw), len(chunk.Chunk)
+	c.block.raw = append(c.block.raw, chunk.Chunk...)
+	chunk.Chunk = nil
+
+	return chunk
+}
+
+// seal compresses the current block.
+func (c *compression) seal() {
+	if c.block != nil {
+		c.block.seal()
+		c.block = nil
+	}
+}
+
+// blockCache keeps the last decompressed block while iterating over the chunks,
+// so the chunks of a block don't decompress it one by one.
+type blockCache struct {
+	block *compressedBlock
+	data  []byte
+}
+
+func (bc *blockCache) bytes(chunk ChunkFromFile) []byte {
+	if chunk.block == nil {
+		return chunk.Chunk
+	}
+
+	if bc.block != chunk.block {
+		bc.block, bc.data = chunk.block, chunk.block.bytes()
+	}
+
+	end := chunk.offset + chunk.size
+
+	return bc.data[chunk.offset:end:end]
+}
+
+// Bytes returns the captured data of the chunk. It's the same as Chunk unless the chunk
+// is stored compressed (see Options.Compress), in which case the data is decompressed on every call.
+// The Result methods decompress every block of the output once.
+func (chunk ChunkFromFile) Bytes() []byte {
+	var cache blockCache
+
+	return cache.bytes(chunk)
+}
+
+// Len returns the length of the captured data of the chunk without decompressing it.
+func (chunk ChunkFromFile) Len() int {
+	if chunk.block == nil {
+		return len(chunk.Chunk)
+	}
+
+	return chunk.size
+}
@@ -0,0 +1,51 @@
+package flowmingo_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestStart_Compress(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{Compress: true},
+		flowmingo.Target{File: os.Stdout}, flowmingo.Target{File: os.Stderr})
+
+	var expected bytes.Buffer
+
+	stdout := capturing.Writer(flowmingo.StdoutName)
+	for i := 0; i < 20000; i++ {
+		line := fmt.Sprintf("line %d of the verbose log\n", i)
+		expected.WriteString(line)
+		_, _ = stdout.Write([]byte(line))
+	}
+
+	_, _ = os.Stderr.WriteString("error")
+	result := capturing.Restore(false)
+
+	assertEqualStrings(t, expected.String(), result.Stdout())
+	assertEqualStrings(t, "error", result.Stderr())
+	assertTrue(t, result[0].Chunk == nil, "Expected the chunk to be stored compressed")
+	assertEqualStrings(t, "line 0 of the verbose log\n", string(result[0].Bytes()))
+	assertEqualInts(t, len("line 0 of the verbose log\n"), result[0].Len())
+
+	var written bytes.Buffer
+	_, err := result.ByName(flowmingo.StdoutName).WriteTo(&written)
+	assertNoError(t, err)
+	assertEqualStrings(t, expected.String(), written.String())
+}
+
+func TestStart_CompressPassesThroughUncompressed(t *testing.T) {
+	outer := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+
+	inner := flowmingo.Start(flowmingo.Options{Compress: true}, flowmingo.Target{File: os.Stdout})
+	_, _ = os.Stdout.WriteString(strings.Repeat("abc", 1000))
+	inner.Restore(true)
+
+	outerResult := outer.Restore(false)
+
+	assertEqualStrings(t, strings.Repeat("abc", 1000), outerResult.Stdout())
+}
@@ -0,0 +1,62 @@
+package flowmingo
+
+import (
+	"fmt"
+	"os"
+)
+
+// crashOutputSetting is the crash output set by a capture.
+type crashOutputSetting struct {
+	capture     *capture
+	crashOutput *os.File
+}
+
+// The crash outputs set by the active captures in the order they were set are guarded by captureLock.
+// The latest one is in effect.
+var crashOutputs []crashOutputSetting
+
+func (c *capture) setCrashOutput(crashOutput *os.File) {
+	if crashOutput == nil {
+		for _, redirection := range c.redirections {
+			if redirection != nil && redirection.fd == stderrFd {
+				crashOutput = redirection.savedFile
+			}
+		}
+
+		if crashOutput == nil || !crashOutputSupported {
+			return
+		}
+	}
+
+	if err := setCrashOutput(crashOutput); err != nil {
+		panic(fmt.Sprintf("cannot set the crash output: %s", err))
+	}
+
+	crashOutputs = append(crashOutputs, crashOutputSetting{capture: c, crashOutput: crashOutput})
+}
+
+// restoreCrashOutput puts the crash output set by the previous active capture back in effect
+// if the crash output of the capture is in effect, or clears the crash output if there is no such capture.
+func (c *capture) restoreCrashOutput() {
+	for i := range crashOutputs {
+		if crashOutputs[i].capture != c {
+			continue
+		}
+
+		inEffect := i == len(crashOutputs)-1
+		crashOutputs = append(crashOutputs[:i:i], crashOutputs[i+1:]...)
+
+		if !inEffect {
+			return
+		}
+
+		var previous *os.File
+		if len(crashOutputs) > 0 {
+			previous = crashOutputs[len(crashOutputs)-1].crashOutput
+		}
+
+		_ = setCrashOutput(previous)
+
+		return
+	}
+}
@@ -0,0 +1,15 @@
+//go:build go1.23
+// +build go1.23
+
+package flowmingo
+
+import (
+	"os"
+	"runtime/debug"
+)
+
+const crashOutputSupported = true
+
+func setCrashOutput(crashOutput *os.File) error {
+	return debug.SetCrashOutput(crashOutput, debug.CrashOptions{})
+}
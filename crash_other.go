@@ -0,0 +1,15 @@
+//go:build !go1.23
+// +build !go1.23
+
+package flowmingo
+
+import (
+	"errors"
+	"os"
+)
+
+const crashOutputSupported = false
+
+func setCrashOutput(*os.File) error {
+	return errors.New("crash output requires Go 1.23 or newer")
+}
@@ -0,0 +1,109 @@
+//go:build go1.23 && (darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+// +build go1.23
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package flowmingo_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+const crashHelperEnv = "FLOWMINGO_CRASH_HELPER"
+
+// TestCrashHelper crashes the process while capturing when run by the tests below.
+func TestCrashHelper(t *testing.T) {
+	crashOutputPath := os.Getenv(crashHelperEnv)
+	if crashOutputPath == "" {
+		t.Skip("run by the crash output tests only")
+	}
+
+	if strings.HasPrefix(crashOutputPath, stackedPrefix) {
+		crashStacked(t, strings.TrimPrefix(crashOutputPath, stackedPrefix))
+	}
+
+	opts := flowmingo.Options{RedirectFDs: crashOutputPath == "-"}
+
+	if !opts.RedirectFDs {
+		crashOutput, err := os.Create(crashOutputPath)
+		assertNoError(t, err)
+
+		opts.CrashOutput = crashOutput
+	}
+
+	flowmingo.Start(opts, flowmingo.Target{File: os.Stderr})
+	panic("crash while capturing")
+}
+
+const stackedPrefix = "stacked:"
+
+// crashStacked crashes the process after restoring a capture stacked on top of the one setting the crash output.
+func crashStacked(t *testing.T, crashOutputPath string) {
+	outerOutput, err := os.Create(crashOutputPath)
+	assertNoError(t, err)
+
+	innerOutput, err := os.Create(crashOutputPath + ".inner")
+	assertNoError(t, err)
+
+	flowmingo.Start(flowmingo.Options{CrashOutput: outerOutput}, flowmingo.Target{File: os.Stderr})
+	flowmingo.Start(flowmingo.Options{CrashOutput: innerOutput}, flowmingo.Target{File: os.Stderr}).Restore(false)
+	panic("crash while capturing")
+}
+
+func runCrashHelper(t *testing.T, crashOutputPath string) string {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCrashHelper$")
+	cmd.Env = append(os.Environ(), crashHelperEnv+"="+crashOutputPath)
+
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("Expected the helper process to crash, got output: %s", output)
+	}
+
+	return string(output)
+}
+
+func TestStart_CrashOutputGetsRuntimeCrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flowmingo")
+	assertNoError(t, err)
+
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	crashOutputPath := filepath.Join(dir, "crash.txt")
+	runCrashHelper(t, crashOutputPath)
+
+	crashOutput, err := ioutil.ReadFile(crashOutputPath)
+	assertNoError(t, err)
+	assertTrue(t, strings.Contains(string(crashOutput), "panic: crash while capturing"), "Unexpected crash output: "+string(crashOutput))
+}
+
+func TestStart_RedirectFDsKeepsRuntimeCrashOnStderr(t *testing.T) {
+	output := runCrashHelper(t, "-")
+
+	assertTrue(t, strings.Contains(output, "panic: crash while capturing"), "Unexpected output: "+output)
+}
+
+func TestStart_RestoreKeepsCrashOutputOfCaptureBelow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flowmingo")
+	assertNoError(t, err)
+
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	crashOutputPath := filepath.Join(dir, "crash.txt")
+	runCrashHelper(t, stackedPrefix+crashOutputPath)
+
+	crashOutput, err := ioutil.ReadFile(crashOutputPath)
+	assertNoError(t, err)
+	assertTrue(t, strings.Contains(string(crashOutput), "panic: crash while capturing"), "Unexpected crash output: "+string(crashOutput))
+
+	innerOutput, err := ioutil.ReadFile(crashOutputPath + ".inner")
+	assertNoError(t, err)
+	assertEqualStrings(t, "", string(innerOutput))
+}
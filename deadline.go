@@ -0,0 +1,81 @@
+package flowmingo
+
+import (
+	"os"
+	"time"
+)
+
+// writeDeadlines are the write deadlines set with SetWriteDeadline by the files, guarded by captureLock.
+var writeDeadlines = make(map[*os.File]time.Time)
+
+// SetWriteDeadline sets the write deadline of the file like (*os.File).SetWriteDeadline does and remembers it
+// (unless setting it fails), so the deadline survives capturing: it's applied to the capture pipe while the file
+// is captured, and it's re-applied to the original file on restore, even if it was set while capturing.
+//
+// The deadlines set with (*os.File).SetWriteDeadline directly can't be carried over, since *os.File doesn't report
+// its deadlines: the deadline set before capturing doesn't apply to the capture pipe (but stays with
+// the original file), and the deadline set while capturing is lost on restore. A zero value of t clears the deadline.
+//
+// The deadline is remembered until the file is restored from the captures (so it must be set again
+// for the later captures), or until its deadline is cleared or the file is found closed by a call to SetWriteDeadline.
+//
+// While the file is captured, the deadline is set on the original file first, so SetWriteDeadline fails
+// for a captured file the same way it fails for the original file, e.g. if the file doesn't support deadlines.
+// Deadlines require Go 1.10 or newer.
+func SetWriteDeadline(f *os.File, t time.Time) error {
+	captureLock.Lock()
+	defer captureLock.Unlock()
+
+	forgetClosedWriteDeadlines()
+
+	if stack := captureStacks[f]; len(stack) > 0 {
+		bottom := stack[0]
+		origOutFile := bottom.origOutFiles[bottom.fileNumber(f)]
+
+		if err := setFileWriteDeadline(&origOutFile, t); err != nil {
+			return err
+		}
+	}
+
+	if err := setFileWriteDeadline(f, t); err != nil {
+		return err
+	}
+
+	// the cleared deadline of a captured file is kept until the file is restored to clear the original file too
+	if t.IsZero() && len(captureStacks[f]) == 0 {
+		delete(writeDeadlines, f)
+	} else {
+		writeDeadlines[f] = t
+	}
+
+	return nil
+}
+
+// applyWriteDeadline applies the deadline remembered for the file to what the file currently is.
+// It must be called with captureLock locked.
+func applyWriteDeadline(f *os.File) {
+	if t, ok := writeDeadlines[f]; ok {
+		_ = setFileWriteDeadline(f, t)
+	}
+}
+
+// forgetWriteDeadline forgets the deadline of the file that's not captured anymore.
+// It must be called with captureLock locked.
+func forgetWriteDeadline(f *os.File) {
+	delete(writeDeadlines, f)
+}
+
+// forgetClosedWriteDeadlines forgets the deadlines of the files closed without being captured,
+// so the closed files are not kept in memory. It must be called with captureLock locked.
+func forgetClosedWriteDeadlines() {
+	for f := range writeDeadlines {
+		if len(captureStacks[f]) > 0 {
+			continue
+		}
+
+		// a closed file fails to write even nothing, but so does a file with the write deadline exceeded
+		if _, err := f.Write(nil); err != nil && !isTimeout(err) {
+			delete(writeDeadlines, f)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+//go:build go1.10
+// +build go1.10
+
+package flowmingo
+
+import (
+	"os"
+	"time"
+)
+
+func setFileWriteDeadline(f *os.File, t time.Time) error {
+	return f.SetWriteDeadline(t)
+}
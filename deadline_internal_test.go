@@ -0,0 +1,53 @@
+//go:build (darwin || dragonfly || freebsd || linux || netbsd || openbsd) && go1.12
+// +build darwin dragonfly freebsd linux netbsd openbsd
+// +build go1.12
+
+package flowmingo
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSetWriteDeadline_DoesntKeepFilesForever(t *testing.T) {
+	restoredR, restoredW, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	defer func() { _ = restoredR.Close() }()
+	defer func() { _ = restoredW.Close() }()
+
+	closedR, closedW, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	defer func() { _ = closedR.Close() }()
+
+	if err = SetWriteDeadline(restoredW, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if err = SetWriteDeadline(closedW, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	Capture(restoredW)(false)
+
+	_ = closedW.Close()
+
+	// a call to SetWriteDeadline forgets the closed files
+	if err = SetWriteDeadline(restoredR, time.Time{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	captureLock.Lock()
+	remembered := len(writeDeadlines)
+	captureLock.Unlock()
+
+	if remembered != 0 {
+		t.Errorf("Expected no remembered deadlines, got %d", remembered)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !go1.10
+// +build !go1.10
+
+package flowmingo
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+func setFileWriteDeadline(*os.File, time.Time) error {
+	return errors.New("deadlines require Go 1.10 or newer")
+}
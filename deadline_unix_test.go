@@ -0,0 +1,104 @@
+//go:build (darwin || dragonfly || freebsd || linux || netbsd || openbsd) && go1.12
+// +build darwin dragonfly freebsd linux netbsd openbsd
+// +build go1.12
+
+package flowmingo_test
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestSetWriteDeadline_AppliedWhileCapturing(t *testing.T) {
+	r, w, err := os.Pipe()
+	assertNoError(t, err)
+
+	defer func() { _ = r.Close() }()
+	defer func() { _ = w.Close() }()
+
+	assertNoError(t, flowmingo.SetWriteDeadline(w, time.Now().Add(-time.Second)))
+
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: w})
+	_, err = w.WriteString("expired\n")
+	assertTrue(t, err != nil, "expected an error")
+	assertEqualInts(t, 0, len(capturing.Restore(false)))
+
+	_, err = w.WriteString("expired\n")
+	assertTrue(t, err != nil, "expected an error")
+
+	assertNoError(t, flowmingo.SetWriteDeadline(w, time.Time{}))
+	_, err = w.WriteString("written\n")
+	assertNoError(t, err)
+}
+
+func TestSetWriteDeadline_ReappliedOnRestore(t *testing.T) {
+	r, w, err := os.Pipe()
+	assertNoError(t, err)
+
+	defer func() { _ = r.Close() }()
+	defer func() { _ = w.Close() }()
+
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: w})
+	assertNoError(t, flowmingo.SetWriteDeadline(w, time.Now().Add(-time.Second)))
+	_, err = w.WriteString("expired\n")
+	assertTrue(t, err != nil, "expected an error")
+	capturing.Restore(false)
+
+	_, err = w.WriteString("expired\n")
+	assertTrue(t, err != nil, "expected an error")
+
+	capturing = flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: w})
+	assertNoError(t, flowmingo.SetWriteDeadline(w, time.Time{}))
+	_, _ = w.WriteString("captured\n")
+	assertEqualStrings(t, "captured\n", string(capturing.Restore(false).Combined()))
+
+	_, err = w.WriteString("written\n")
+	assertNoError(t, err)
+}
+
+func TestSetWriteDeadline_FailsLikeOriginalFile(t *testing.T) {
+	var fds [2]int
+
+	assertNoError(t, syscall.Pipe(fds[:]))
+
+	r := os.NewFile(uintptr(fds[0]), "r")
+	w := os.NewFile(uintptr(fds[1]), "w")
+
+	defer func() { _ = r.Close() }()
+	defer func() { _ = w.Close() }()
+
+	assertTrue(t, w.SetWriteDeadline(time.Now()) != nil, "expected an error")
+
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: w})
+	assertTrue(t, flowmingo.SetWriteDeadline(w, time.Now().Add(time.Second)) != nil, "expected an error")
+	_, _ = w.WriteString("captured\n")
+	assertEqualStrings(t, "captured\n", string(capturing.Restore(false).Combined()))
+
+	assertTrue(t, w.SetWriteDeadline(time.Now()) != nil, "expected an error")
+}
+
+func TestStart_RedirectFDsKeepsNonBlockingMode(t *testing.T) {
+	r, w, err := os.Pipe()
+	assertNoError(t, err)
+
+	defer func() { _ = r.Close() }()
+	defer func() { _ = w.Close() }()
+
+	capturing := flowmingo.Start(flowmingo.Options{RedirectFDs: true}, flowmingo.Target{File: w})
+	_, _ = w.WriteString("captured\n")
+	assertEqualStrings(t, "captured\n", string(capturing.Restore(false).Combined()))
+
+	rawConn, err := w.SyscallConn()
+	assertNoError(t, err)
+
+	var flags uintptr
+
+	assertNoError(t, rawConn.Control(func(fd uintptr) {
+		flags, _, _ = syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_GETFL, 0)
+	}))
+	assertTrue(t, flags&syscall.O_NONBLOCK != 0, "expected the file to stay non-blocking")
+}
@@ -30,11 +30,7 @@ func Example_captureStdout() {
 	fmt.Println("This will not be captured")
 
 	// Analyze the captured output
-	fmt.Print("captured: ")
-
-	for _, chunk := range capturedOutput {
-		fmt.Printf("%s", chunk.Chunk)
-	}
+	fmt.Printf("captured: %s", capturedOutput.Stdout())
 	// Output:
 	// This will be captured
 	// This will not be captured
@@ -77,13 +73,9 @@ func ExampleCaptureStdoutAndStderr() {
 	// skipping the pass-through for this time
 	capturedOutput := restore(false)
 
+	// The chunks are labeled with the names of the outputs they were written to
 	for _, chunk := range capturedOutput {
-		source := "stdout"
-		if chunk.OutFile == os.Stderr {
-			source = "stderr"
-		}
-
-		fmt.Printf("captured: %s: %s", source, chunk.Chunk)
+		fmt.Printf("captured: %s: %s", chunk.Name, chunk.Chunk)
 	}
 	// Output:
 	// captured: stdout: This will be captured
@@ -220,3 +212,30 @@ func Example_captureCmdOutputs() {
 	// Output:
 	// captured: out: test
 }
+
+// Example_captureCmdExtraFiles demonstrates how to capture the output of an external command
+// including the output to its extra file descriptors.
+//
+//nolint:nosnakecase // example for the package
+func Example_captureCmdExtraFiles() {
+	// Create a command writing to the descriptor 3
+	cmd := exec.Command("sh", "-c", "echo test; echo diagnostics >&3")
+
+	// Capture the command's stdout and stderr along with the descriptor 3 labeled as "diag"
+	getOuts := flowmingo.CaptureCmd(cmd, "diag")
+
+	// Run the command
+	err := cmd.Run()
+	if err != nil {
+		panic(fmt.Sprintf("Error running the command: %s", err))
+	}
+
+	capturedOutput := getOuts(false)
+
+	fmt.Printf("captured: stdout: %s", capturedOutput.Stdout())
+	fmt.Printf("captured: diag: %s", capturedOutput.ByName("diag").Combined())
+
+	// Output:
+	// captured: stdout: test
+	// captured: diag: diagnostics
+}
@@ -0,0 +1,10 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package flowmingo
+
+import "syscall"
+
+func dup2(oldFd, newFd int) error {
+	return syscall.Dup2(oldFd, newFd)
+}
@@ -0,0 +1,8 @@
+package flowmingo
+
+import "syscall"
+
+// dup2 is implemented with dup3, since some Linux architectures don't have dup2.
+func dup2(oldFd, newFd int) error {
+	return syscall.Dup3(oldFd, newFd, 0)
+}
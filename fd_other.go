@@ -0,0 +1,27 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package flowmingo
+
+import (
+	"errors"
+	"os"
+)
+
+const stderrFd = 2
+
+const redirectSupported = false
+
+var errRedirectNotSupported = errors.New("file descriptor redirection is not supported on this platform")
+
+func newBlockingPipe() (outR, outW *os.File, outWFd int, err error) {
+	return nil, nil, -1, errRedirectNotSupported
+}
+
+func redirectFd(int, int) (int, error) {
+	return -1, errRedirectNotSupported
+}
+
+func restoreFd(int, int) error {
+	return errRedirectNotSupported
+}
@@ -0,0 +1,64 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package flowmingo
+
+import (
+	"os"
+	"syscall"
+)
+
+const stderrFd = 2
+
+const redirectSupported = true
+
+// newBlockingPipe creates a pipe with the blocking write end, so the writers sharing
+// the write end by a redirected descriptor block instead of getting EAGAIN when the pipe is full.
+func newBlockingPipe() (outR, outW *os.File, outWFd int, err error) {
+	var fds [2]int
+
+	syscall.ForkLock.RLock()
+
+	err = syscall.Pipe(fds[:])
+	if err == nil {
+		syscall.CloseOnExec(fds[0])
+		syscall.CloseOnExec(fds[1])
+	}
+	syscall.ForkLock.RUnlock()
+
+	if err != nil {
+		return nil, nil, -1, os.NewSyscallError("pipe", err)
+	}
+
+	return os.NewFile(uintptr(fds[0]), "|0"), os.NewFile(uintptr(fds[1]), "|1"), fds[1], nil
+}
+
+// redirectFd makes fd refer to the file toFd refers to and returns a close-on-exec duplicate of the original fd.
+func redirectFd(fd, toFd int) (savedFd int, err error) {
+	syscall.ForkLock.RLock()
+	defer syscall.ForkLock.RUnlock()
+
+	savedFd, err = syscall.Dup(fd)
+	if err != nil {
+		return -1, os.NewSyscallError("dup", err)
+	}
+
+	syscall.CloseOnExec(savedFd)
+
+	if err = dup2(toFd, fd); err != nil {
+		_ = syscall.Close(savedFd)
+
+		return -1, os.NewSyscallError("dup2", err)
+	}
+
+	return savedFd, nil
+}
+
+// restoreFd makes fd refer to the file savedFd refers to. It doesn't close savedFd.
+func restoreFd(fd, savedFd int) error {
+	if err := dup2(savedFd, fd); err != nil {
+		return os.NewSyscallError("dup2", err)
+	}
+
+	return nil
+}
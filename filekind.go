@@ -0,0 +1,102 @@
+package flowmingo
+
+import "os"
+
+// FileKind is the kind of an output file as detected with Stat when the capture starts.
+//
+// The kind tells how the capture behaves for the file:
+//   - Regular files keep their offset and flags (like O_APPEND) since the capture doesn't touch the original
+//     descriptor: the output passed through is written at the position the writes would have gone to, and
+//     without passing through the offset stays where it was. Seek, Truncate and Sync called on a captured file
+//     fail, since they go to the capture pipe.
+//   - For FIFOs and sockets, the reader on the other end sees nothing while capturing. Closing the file while
+//     capturing closes the original file on restore, so the reader gets EOF as it would without capturing.
+//     The write deadlines go to the capture pipe while capturing.
+//   - Devices (terminals, /dev/null, etc.) behave like regular files except for the offset.
+//
+// Closing any captured file closes the original file on restore, so the file stays closed.
+// The exceptions are os.Stdout and os.Stderr: the descriptors 1 and 2 of the process are never closed by restoring.
+type FileKind int
+
+// The kinds of the output files.
+const (
+	FileKindUnknown FileKind = iota // Stat failed
+	FileKindRegular
+	FileKindPipe // a FIFO or an anonymous pipe
+	FileKindSocket
+	FileKindDevice
+	FileKindOther
+)
+
+// String returns the name of the kind like "regular" or "pipe".
+func (k FileKind) String() string {
+	switch k {
+	case FileKindRegular:
+		return "regular"
+	case FileKindPipe:
+		return "pipe"
+	case FileKindSocket:
+		return "socket"
+	case FileKindDevice:
+		return "device"
+	case FileKindOther:
+		return "other"
+	default:
+		return "unknown"
+	}
+}
+
+func detectFileKind(file *os.File) FileKind {
+	info, err := file.Stat()
+	if err != nil {
+		return FileKindUnknown
+	}
+
+	mode := info.Mode()
+
+	switch {
+	case mode.IsRegular():
+		return FileKindRegular
+	case mode&os.ModeNamedPipe != 0:
+		return FileKindPipe
+	case mode&os.ModeSocket != 0:
+		return FileKindSocket
+	case mode&os.ModeDevice != 0:
+		return FileKindDevice
+	default:
+		return FileKindOther
+	}
+}
+
+// closedOutFiles tells which output files were closed by the user while capturing.
+// Only the files the capture is on the top of the stacks of are checked, since closing
+// a file with another capture stacked on top of it closes the pipe of the other capture.
+// The standard streams are never reported, so the descriptors 1 and 2 are not closed by a guess.
+func (c *capture) closedOutFiles() []bool {
+	closed := make([]bool, len(c.outFiles))
+
+	for outFileNumber, outFile := range c.outFiles {
+		if c.owned || outFile == os.Stdout || outFile == os.Stderr || captureAbove(c, outFile) != nil {
+			continue
+		}
+
+		// a closed file fails to write even nothing, but so does a file with the write deadline exceeded
+		probe := c.outWFiles[outFileNumber]
+		if c.redirections[outFileNumber] != nil {
+			origOutFile := c.origOutFiles[outFileNumber]
+			probe = &origOutFile
+		}
+
+		if _, err := probe.Write(nil); err != nil && !isTimeout(err) {
+			closed[outFileNumber] = true
+		}
+	}
+
+	return closed
+}
+
+func isTimeout(err error) bool {
+	timeout, ok := err.(interface{ Timeout() bool })
+
+	return ok && timeout.Timeout()
+}
@@ -0,0 +1,179 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package flowmingo_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestStart_RegularFileKeepsOffset(t *testing.T) {
+	file, err := ioutil.TempFile("", "flowmingo")
+	assertNoError(t, err)
+
+	defer func() { _ = os.Remove(file.Name()) }()
+	defer func() { _ = file.Close() }()
+
+	_, _ = file.WriteString("header\n")
+
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: file})
+	assertEqualStrings(t, "regular", capturing.Info().Targets[0].Kind.String())
+	_, _ = file.WriteString("dropped\n")
+	capturing.Restore(false)
+
+	offset, err := file.Seek(0, io.SeekCurrent)
+	assertNoError(t, err)
+	assertEqualInts(t, len("header\n"), int(offset))
+
+	capturing = flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: file})
+	_, _ = file.WriteString("passed\n")
+	capturing.Restore(true)
+	_, _ = file.WriteString("footer\n")
+
+	content, err := ioutil.ReadFile(file.Name())
+	assertNoError(t, err)
+	assertEqualStrings(t, "header\npassed\nfooter\n", string(content))
+}
+
+func TestStart_RegularFileKeepsAppendFlag(t *testing.T) {
+	tempFile, err := ioutil.TempFile("", "flowmingo")
+	assertNoError(t, err)
+
+	path := tempFile.Name()
+	_ = tempFile.Close()
+
+	defer func() { _ = os.Remove(path) }()
+
+	assertNoError(t, ioutil.WriteFile(path, []byte("abc\n"), 0600))
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	assertNoError(t, err)
+
+	defer func() { _ = file.Close() }()
+
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: file})
+	_, _ = file.WriteString("captured\n")
+
+	// written by someone else while capturing
+	other, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	assertNoError(t, err)
+	_, _ = other.WriteString("other\n")
+	_ = other.Close()
+
+	// the captured output passed through is appended after the output of the other writer
+	capturing.Restore(true)
+
+	_, _ = file.WriteString("def\n")
+
+	content, err := ioutil.ReadFile(path)
+	assertNoError(t, err)
+	assertEqualStrings(t, "abc\nother\ncaptured\ndef\n", string(content))
+}
+
+func TestStart_ClosingStdoutDoesntCloseDescriptor(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+	_ = os.Stdout.Close()
+	capturing.Restore(false)
+
+	_, err := os.Stdout.Write(nil)
+	assertNoError(t, err)
+}
+
+func TestStart_FIFO(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flowmingo")
+	assertNoError(t, err)
+
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "fifo")
+	assertNoError(t, syscall.Mkfifo(path, 0600))
+
+	reader, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	assertNoError(t, err)
+
+	defer func() { _ = reader.Close() }()
+
+	writer, err := os.OpenFile(path, os.O_WRONLY, 0)
+	assertNoError(t, err)
+
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: writer, Name: "fifo"})
+	assertEqualStrings(t, "pipe", capturing.Info().Targets[0].Kind.String())
+	_, _ = writer.WriteString("abc")
+
+	// closing the file while capturing closes the original on restore
+	assertNoError(t, writer.Close())
+
+	result := capturing.Restore(true)
+
+	assertEqualStrings(t, "abc", string(result.ByName("fifo").Combined()))
+
+	_, err = writer.WriteString("def")
+	assertTrue(t, err != nil, "Expected the file to stay closed")
+
+	assertNoError(t, syscall.SetNonblock(int(reader.Fd()), false))
+
+	data, err := ioutil.ReadAll(reader)
+	assertNoError(t, err)
+	assertEqualStrings(t, "abc", string(data))
+}
+
+func TestStart_Socket(t *testing.T) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
+	assertNoError(t, err)
+
+	writer := os.NewFile(uintptr(fds[0]), "socket")
+	reader := os.NewFile(uintptr(fds[1]), "socket")
+
+	defer func() { _ = reader.Close() }()
+
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: writer})
+	assertEqualStrings(t, "socket", capturing.Info().Targets[0].Kind.String())
+	_, _ = writer.WriteString("captured")
+	capturing.Restore(false)
+
+	_, _ = writer.WriteString("abc")
+	assertNoError(t, writer.Close())
+
+	data, err := ioutil.ReadAll(reader)
+	assertNoError(t, err)
+	assertEqualStrings(t, "abc", string(data))
+}
+
+func TestStart_Device(t *testing.T) {
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	assertNoError(t, err)
+
+	defer func() { _ = devNull.Close() }()
+
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: devNull})
+	assertEqualStrings(t, "device", capturing.Info().Targets[0].Kind.String())
+	_, _ = devNull.WriteString("abc")
+	result := capturing.Restore(true)
+
+	assertEqualStrings(t, "abc", string(result.Combined()))
+}
+
+func TestStart_RedirectedFileClosedWhileCapturingStaysClosed(t *testing.T) {
+	file, err := ioutil.TempFile("", "flowmingo")
+	assertNoError(t, err)
+
+	defer func() { _ = os.Remove(file.Name()) }()
+
+	capturing := flowmingo.Start(flowmingo.Options{RedirectFDs: true}, flowmingo.Target{File: file})
+	_, _ = file.WriteString("abc")
+	assertNoError(t, file.Close())
+	result := capturing.Restore(true)
+
+	assertEqualStrings(t, "abc", string(result.Combined()))
+
+	content, err := ioutil.ReadFile(file.Name())
+	assertNoError(t, err)
+	assertEqualStrings(t, "abc", string(content))
+}
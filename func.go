@@ -0,0 +1,79 @@
+package flowmingo
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+)
+
+// FuncOptions configures CaptureFunc.
+type FuncOptions struct {
+	// Options configures the capture.
+	Options
+
+	// PassThroughOuts tells whether the captured output should be written to the original output files on restore.
+	PassThroughOuts bool
+	// RePanic makes CaptureFunc panic again with the value the function panicked with after restoring
+	// the output files instead of returning the value as a *PanicError.
+	RePanic bool
+}
+
+// PanicError is returned by CaptureFunc when the function panics.
+type PanicError struct {
+	// Value is the value the function panicked with.
+	Value interface{}
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+// Error returns the panic value along with the stack trace like the runtime would print them.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n\n%s", e.Value, e.Stack)
+}
+
+// CaptureFunc captures the output to the given output files while running fn and returns the captured output.
+//
+// The output files are always restored, even if fn panics or calls runtime.Goexit.
+// If fn panics, the output produced up to the panic is returned along with a *PanicError,
+// or, if opts.RePanic is set, CaptureFunc panics again with the same value after restoring,
+// so the panic message reaches the original STDERR instead of the pipe.
+//
+// With opts.RedirectFDs and opts.CrashOutput, the output the Go runtime writes on crashes can be captured as well.
+//
+// The output files are checked the same way Capture checks them.
+func CaptureFunc(outFiles []*os.File, fn func(), opts FuncOptions) (result Result, err error) {
+	mustNotBeEmpty(outFiles)
+	mustNotContainNils(outFiles)
+	mustNotContainDuplicates(outFiles)
+
+	restore := Start(opts.Options, defaultTargets(outFiles)...).Restore
+
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			result = restore(opts.PassThroughOuts)
+
+			return
+		}
+
+		// the stack of fn is still there while running the deferred functions
+		stack := debug.Stack()
+
+		// the panic of fn is reported even if restoring panics too, e.g. because fn replaced an output file
+		func() {
+			defer func() { _ = recover() }()
+
+			result = restore(opts.PassThroughOuts)
+		}()
+
+		if opts.RePanic {
+			panic(recovered)
+		}
+
+		err = &PanicError{Value: recovered, Stack: stack}
+	}()
+
+	fn()
+
+	return nil, nil
+}
@@ -0,0 +1,44 @@
+package flowmingo
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestCaptureFunc_ReportsPanicOfFuncWhenRestorePanics(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	defer func() { _ = reader.Close() }()
+	defer func() { _ = writer.Close() }()
+
+	var captured os.File
+
+	_, err = CaptureFunc([]*os.File{writer}, func() {
+		// restoring panics since the output file is changed from the outside
+		captured = *writer
+		*writer = *reader
+		panic("boom")
+	}, FuncOptions{})
+
+	panicErr, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("Expected *PanicError, got %v", err)
+	}
+
+	if value := fmt.Sprint(panicErr.Value); value != "boom" {
+		t.Errorf("Expected the panic of the function, got %s", value)
+	}
+
+	// Clean up the broken capture
+	*writer = captured
+
+	captureLock.Lock()
+	c := activeCaptures[len(activeCaptures)-1]
+	captureLock.Unlock()
+
+	c.restore(false, nil, nil)
+}
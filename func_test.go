@@ -0,0 +1,77 @@
+package flowmingo_test
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestCaptureFunc_CapturesOutput(t *testing.T) {
+	result, err := flowmingo.CaptureFunc([]*os.File{os.Stdout, os.Stderr}, func() {
+		fmt.Print("out")
+	}, flowmingo.FuncOptions{})
+
+	assertNoError(t, err)
+	assertEqualStrings(t, "out", result.Stdout())
+}
+
+func TestCaptureFunc_ReturnsPanicAlongWithOutput(t *testing.T) {
+	result, err := flowmingo.CaptureFunc([]*os.File{os.Stdout}, func() {
+		fmt.Print("before panic")
+		panic("boom")
+	}, flowmingo.FuncOptions{})
+
+	panicErr, ok := err.(*flowmingo.PanicError)
+	if !ok {
+		t.Fatalf("Expected *PanicError, got %v", err)
+	}
+
+	assertEqualStrings(t, "boom", fmt.Sprint(panicErr.Value))
+	assertTrue(t, strings.HasPrefix(panicErr.Error(), "panic: boom\n\n"), "Unexpected error: "+panicErr.Error())
+	// the frame of the panicking function, not only the frame of the test calling CaptureFunc
+	assertTrue(t, strings.Contains(string(panicErr.Stack), "TestCaptureFunc_ReturnsPanicAlongWithOutput.func1"),
+		"Unexpected stack: "+string(panicErr.Stack))
+	assertEqualStrings(t, "before panic", result.Stdout())
+}
+
+func TestCaptureFunc_RePanicsAfterRestoring(t *testing.T) {
+	origStdout := *os.Stdout
+
+	recovered := recoverPanic(func() {
+		_, _ = flowmingo.CaptureFunc([]*os.File{os.Stdout}, func() {
+			panic("boom")
+		}, flowmingo.FuncOptions{RePanic: true})
+	})
+
+	assertEqualStrings(t, "boom", fmt.Sprint(recovered))
+	assertTrue(t, *os.Stdout == origStdout, "Expected os.Stdout to be restored")
+}
+
+func TestCaptureFunc_RestoresOnGoexit(t *testing.T) {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		// CaptureFunc doesn't return here, but the output files must be restored anyway
+		_, _ = flowmingo.CaptureFunc([]*os.File{os.Stdout}, func() {
+			fmt.Print("before exit")
+			runtime.Goexit()
+		}, flowmingo.FuncOptions{})
+	}()
+	<-done
+
+	assertNoError(t, flowmingo.VerifyNoActiveCaptures())
+}
+
+func recoverPanic(funcToCall func()) (recovered interface{}) {
+	defer func() { recovered = recover() }()
+
+	funcToCall()
+
+	return nil
+}
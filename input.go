@@ -0,0 +1,104 @@
+package flowmingo
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Input is the input injected into a file by InjectInput.
+// The program reading the file reads what is written to the Input.
+type Input struct {
+	file       *os.File
+	inR, inW   *os.File
+	inFile     os.File // the contents of the file while injecting
+	origInFile os.File // the contents of the file to restore
+
+	lock     sync.Mutex
+	restored bool
+}
+
+// InjectInput replaces the contents of the given file (e.g. os.Stdin) with the read end of a pipe
+// like Capture does for the output files, and returns the Input writing to the pipe. This way the code reading
+// the file can be tested in-process along with capturing its output, e.g. in combination with (*Capturing).WaitFor:
+//
+//	input := flowmingo.InjectStdin()
+//	defer input.Restore()
+//
+//	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+//	go runCLI()
+//
+//	_ = capturing.WaitFor(flowmingo.StdoutName, regexp.MustCompile(`Name: $`), time.Second)
+//	_ = input.WriteLine("gopher")
+//
+// The writes to the Input block while the pipe is full, until the program reads the input.
+// The Input must be restored with Restore. Unlike captures, the inputs injected into the same file
+// must be restored in the reverse order, and they are not tracked by ActiveCaptures, VerifyNoActiveCaptures
+// and SetLeakHandler, so a leaked Input goes unnoticed.
+func InjectInput(inFile *os.File) *Input {
+	if inFile == nil {
+		panic("input file is nil, nil pointers are not allowed")
+	}
+
+	captureLock.Lock()
+	defer captureLock.Unlock()
+
+	inR, inW, err := os.Pipe()
+	if err != nil {
+		panic(fmt.Sprintf("cannot create a pipe for input file %q: %s", inFile.Name(), err))
+	}
+
+	in := &Input{file: inFile, inR: inR, inW: inW, inFile: *inR}
+	replaceOutFile(inFile, inR, &in.origInFile)
+
+	return in
+}
+
+// InjectStdin injects the input into os.Stdin. See InjectInput.
+func InjectStdin() *Input {
+	return InjectInput(os.Stdin)
+}
+
+// Write writes the input. It implements io.Writer.
+func (in *Input) Write(p []byte) (int, error) {
+	return in.inW.Write(p)
+}
+
+// WriteString writes the input given as a string.
+func (in *Input) WriteString(s string) (int, error) {
+	return io.WriteString(in.inW, s)
+}
+
+// WriteLine writes the line followed by a newline.
+func (in *Input) WriteLine(line string) error {
+	_, err := in.inW.Write([]byte(line + "\n"))
+
+	return err
+}
+
+// Close closes the input, so the program reading the file gets EOF once it reads all the input written before.
+// The file stays injected until Restore.
+func (in *Input) Close() error {
+	return in.inW.Close()
+}
+
+// Restore restores the contents of the file and closes the pipe. Restore can be called more than once.
+func (in *Input) Restore() {
+	captureLock.Lock()
+	defer captureLock.Unlock()
+
+	in.lock.Lock()
+	defer in.lock.Unlock()
+
+	if in.restored {
+		return
+	}
+
+	restoreOutFiles("injected input", []string{in.origInFile.Name()},
+		[]*os.File{in.file}, []os.File{in.inFile}, []os.File{in.origInFile})
+	in.restored = true
+
+	_ = in.inW.Close()
+	_ = in.inR.Close()
+}
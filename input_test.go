@@ -0,0 +1,76 @@
+package flowmingo_test
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestInjectStdin(t *testing.T) {
+	origStdin := *os.Stdin
+
+	input := flowmingo.InjectStdin()
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+
+	done := make(chan bool)
+
+	go func() {
+		defer close(done)
+
+		reader := bufio.NewReader(os.Stdin)
+		for i := 0; i < 2; i++ {
+			fmt.Print("Name: ")
+
+			name, _ := reader.ReadString('\n')
+			fmt.Printf("Hello, %s", name)
+		}
+	}()
+
+	assertNoError(t, capturing.WaitFor(flowmingo.StdoutName, regexp.MustCompile(`Name: $`), 5*time.Second))
+	assertNoError(t, input.WriteLine("gopher"))
+	assertNoError(t, capturing.WaitFor(flowmingo.StdoutName, regexp.MustCompile(`Hello, gopher\n`), 5*time.Second))
+
+	// the output is consumed up to the end of the previous match
+	assertNoError(t, capturing.WaitFor(flowmingo.StdoutName, regexp.MustCompile(`Name: $`), 5*time.Second))
+	assertNoError(t, input.WriteLine("world"))
+	<-done
+
+	result := capturing.Restore(false)
+	input.Restore()
+
+	assertEqualStrings(t, "Name: Hello, gopher\nName: Hello, world\n", result.Stdout())
+	assertTrue(t, *os.Stdin == origStdin, "Expected os.Stdin to be restored")
+}
+
+func TestInput_Close(t *testing.T) {
+	input := flowmingo.InjectStdin()
+	defer input.Restore()
+
+	_, _ = input.WriteString("abc")
+	assertNoError(t, input.Close())
+
+	data, err := ioutil.ReadAll(os.Stdin)
+	assertNoError(t, err)
+	assertEqualStrings(t, "abc", string(data))
+}
+
+func TestCapturing_WaitForTimesOut(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+	_, _ = os.Stdout.WriteString("Loading...")
+
+	err := capturing.WaitFor(flowmingo.StdoutName, regexp.MustCompile(`Done`), 100*time.Millisecond)
+	assertTrue(t, err != nil && strings.Contains(err.Error(), "Loading..."), "Expected a timeout error with the output")
+
+	capturing.Restore(false)
+
+	err = capturing.WaitFor(flowmingo.StdoutName, regexp.MustCompile(`Done`), time.Hour)
+	_, ok := err.(*flowmingo.WaitTimeoutError)
+	assertTrue(t, ok, "Expected WaitFor to return right after the restore")
+}
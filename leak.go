@@ -0,0 +1,94 @@
+package flowmingo
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// LeakedCapturesError is returned by VerifyNoActiveCaptures when there are captures that haven't been restored.
+type LeakedCapturesError struct {
+	Captures []CaptureInfo
+}
+
+// Error lists the leaked captures along with the stack traces of the calls that created them.
+func (e *LeakedCapturesError) Error() string {
+	descriptions := make([]string, len(e.Captures))
+	for i, info := range e.Captures {
+		descriptions[i] = fmt.Sprintf("%s\n%s", info, info.Stack)
+	}
+
+	return fmt.Sprintf("found %d unrestored capture(s):\n\n%s", len(e.Captures), strings.Join(descriptions, "\n"))
+}
+
+// VerifyNoActiveCaptures returns a *LeakedCapturesError if there are captures whose restore functions
+// haven't been called. A leaked capture keeps its goroutines running and its output files hijacked.
+//
+// It's intended to be called from TestMain after running the tests:
+//
+//	func TestMain(m *testing.M) {
+//		code := m.Run()
+//		if err := flowmingo.VerifyNoActiveCaptures(); err != nil {
+//			fmt.Fprintln(os.Stderr, err)
+//			code = 1
+//		}
+//		os.Exit(code)
+//	}
+//
+// The inputs injected with InjectInput are not captures and are not tracked: neither VerifyNoActiveCaptures
+// nor the leak handler (see SetLeakHandler) report an Input left unrestored with its file (e.g. os.Stdin) hijacked.
+func VerifyNoActiveCaptures() error {
+	if infos := ActiveCaptures(); len(infos) > 0 {
+		return &LeakedCapturesError{Captures: infos}
+	}
+
+	return nil
+}
+
+// The leak handler is guarded by captureLock.
+var leakHandler func(info CaptureInfo)
+
+// SetLeakHandler sets the function to be called when the restore function of a capture
+// (or the *Capturing returned by Start) becomes unreachable without being called. The function is called from a finalizer,
+// so it's called in a separate goroutine some time after a garbage collection.
+//
+// Only the captures started after the handler is set are watched. Passing nil stops watching new captures.
+// The inputs injected with InjectInput are not watched.
+//
+// Note that the output files of a leaked capture may still be captured when the handler is called,
+// so the handler should not report to them.
+func SetLeakHandler(handler func(info CaptureInfo)) {
+	captureLock.Lock()
+	defer captureLock.Unlock()
+
+	leakHandler = handler
+}
+
+// watchForLeak returns the handle of the capture
+// that makes the leak handler get called if the handle is dropped without restoring the capture.
+// The handler is the one read under captureLock when the capture was registered.
+func watchForLeak(c *capture) *Capturing {
+	handle := &Capturing{capture: c}
+	if c.leakHandler == nil {
+		return handle
+	}
+
+	handler := c.leakHandler
+
+	runtime.SetFinalizer(handle, func(handle *Capturing) {
+		captureLock.Lock()
+		restored := handle.capture.outC == nil
+
+		var info CaptureInfo
+		if !restored {
+			info = handle.capture.info()
+		}
+		captureLock.Unlock()
+
+		if !restored {
+			handler(info)
+		}
+	})
+
+	return handle
+}
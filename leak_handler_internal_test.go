@@ -0,0 +1,54 @@
+package flowmingo
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSetLeakHandler_ReportsDroppedRestoreFunc(t *testing.T) {
+	leaked := make(chan CaptureInfo, 1)
+
+	SetLeakHandler(func(info CaptureInfo) { leaked <- info })
+	defer SetLeakHandler(nil)
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	defer func() { _ = reader.Close() }()
+	defer func() { _ = writer.Close() }()
+
+	// The restore function of this capture is dropped right away
+	Capture(writer)
+
+	// The restore function of this capture is called, so it's not reported
+	Capture(os.Stdout)(false)
+
+	var info CaptureInfo
+
+	for deadline := time.Now().Add(5 * time.Second); info.ID == 0 && time.Now().Before(deadline); {
+		runtime.GC()
+
+		select {
+		case info = <-leaked:
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if info.ID == 0 {
+		t.Fatal("The leak handler was not called")
+	}
+
+	if len(info.Targets) != 1 || info.Targets[0].File != writer {
+		t.Errorf("Unexpected leaked capture: %s", info)
+	}
+
+	// Clean up the leaked capture
+	captureLock.Lock()
+	leakedCapture := activeCaptures[len(activeCaptures)-1]
+	captureLock.Unlock()
+	leakedCapture.restore(false, nil, nil)
+}
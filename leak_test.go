@@ -0,0 +1,29 @@
+package flowmingo_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestVerifyNoActiveCaptures(t *testing.T) {
+	assertNoError(t, flowmingo.VerifyNoActiveCaptures())
+
+	restore := flowmingo.Capture(os.Stdout)
+	err := flowmingo.VerifyNoActiveCaptures()
+	restore(false)
+
+	leakErr, ok := err.(*flowmingo.LeakedCapturesError)
+	if !ok {
+		t.Fatalf("Expected *LeakedCapturesError, got %v", err)
+	}
+
+	assertEqualInts(t, 1, len(leakErr.Captures))
+	assertTrue(t, strings.HasPrefix(leakErr.Error(), "found 1 unrestored capture(s):\n\ncapture #"), "Unexpected error: "+leakErr.Error())
+	assertTrue(t, strings.Contains(leakErr.Error(), "leak_test.go:14"), "Unexpected error: "+leakErr.Error())
+	assertTrue(t, strings.Contains(leakErr.Error(), "TestVerifyNoActiveCaptures"), "Unexpected error: "+leakErr.Error())
+
+	assertNoError(t, flowmingo.VerifyNoActiveCaptures())
+}
@@ -0,0 +1,21 @@
+package flowmingo_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+
+	if err := flowmingo.VerifyNoActiveCaptures(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		code = 1
+	}
+
+	os.Exit(code)
+}
@@ -0,0 +1,11 @@
+//go:build (!darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd) || !go1.12
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd !go1.12
+
+package flowmingo
+
+import "os"
+
+// fileFd returns the descriptor of the file switching it to the blocking mode, since there is no other way here.
+func fileFd(f *os.File) (int, error) {
+	return int(f.Fd()), nil
+}
@@ -0,0 +1,26 @@
+//go:build (darwin || dragonfly || freebsd || linux || netbsd || openbsd) && go1.12
+// +build darwin dragonfly freebsd linux netbsd openbsd
+// +build go1.12
+
+package flowmingo
+
+import "os"
+
+// fileFd returns the descriptor of the file without switching it to the blocking mode unlike (*os.File).Fd,
+// so the file keeps supporting deadlines.
+func fileFd(f *os.File) (int, error) {
+	rawConn, err := f.SyscallConn()
+	if err != nil {
+		return -1, err
+	}
+
+	fd := -1
+
+	if err = rawConn.Control(func(rawFd uintptr) {
+		fd = int(rawFd)
+	}); err != nil {
+		return -1, err
+	}
+
+	return fd, nil
+}
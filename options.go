@@ -0,0 +1,223 @@
+package flowmingo
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Options configures a capture started with Start.
+// The zero value gives the behavior of Capture.
+type Options struct {
+	// RedirectFDs makes the capture redirect the file descriptors of the output files to the capture pipes
+	// (with dup2) instead of replacing the contents of the *os.File pointers. This way the capture also gets
+	// the output written to the descriptors directly, bypassing the *os.File values, e.g. the panic traces
+	// and the fatal errors the Go runtime writes to the descriptor 2, the output of the C code,
+	// and the output of the child processes started while capturing.
+	//
+	// The output passed through goes to the duplicates of the original descriptors.
+	// The child processes keeping a redirected descriptor open make the restore wait for them to exit.
+	//
+	// A capture with RedirectFDs can't be restored while another capture is stacked on top of it
+	// for the same output file if the other capture redirects the descriptor too.
+	//
+	// It's supported on Linux, macOS and BSDs only.
+	RedirectFDs bool
+
+	// CrashOutput is the file to write the crash output of the Go runtime to (in addition to STDERR)
+	// while capturing: unhandled panics and fatal errors (see debug.SetCrashOutput). The crash text reaches
+	// the file before the process dies, so it can be attached to reports even if the process can't restore.
+	//
+	// If CrashOutput is nil and RedirectFDs redirects the descriptor 2, the crash output goes to the duplicate
+	// of the original descriptor 2, so the crash isn't lost in the pipe.
+	//
+	// There is only one crash output per process, so the latest capture setting it wins. Restoring the capture
+	// puts the crash output of the previous active capture setting it back, or clears the crash output if there is
+	// no such capture. Note that the crash output set by the application with debug.SetCrashOutput is replaced
+	// (also implicitly with RedirectFDs as described above) and is not put back on restore, since there is no way
+	// to get it, so the application has to set it again after restoring.
+	// It requires Go 1.23 or newer.
+	CrashOutput *os.File
+
+	// PipeSize is the minimum capacity of the capture pipes in bytes. When a writer bursts faster than the capture
+	// drains the pipe, the writer blocks once the pipe is full, so a larger pipe keeps bursty writers from stalling.
+	// Zero keeps the default capacity (64 KiB on most systems).
+	//
+	// The pipes are enlarged with fcntl(F_SETPIPE_SZ) on Linux (with Go 1.12 or newer) only, other platforms ignore
+	// PipeSize. The kernel rounds the capacity up and caps it for unprivileged processes at /proc/sys/fs/pipe-max-size.
+	// The capacity actually applied is reported by TargetInfo.PipeCapacity.
+	PipeSize int
+
+	// CollectStats makes the capture collect the runtime statistics available via (*Capturing).Stats.
+	// It costs an extra system call per chunk on Linux.
+	CollectStats bool
+
+	// WriteBoundaries makes the capture keep the boundaries of the writes, so every write to an output file
+	// becomes a separate chunk instead of being merged with the adjacent writes. It's useful for testing protocols
+	// where what each Write call contained matters.
+	//
+	// It uses the packet-mode pipes (O_DIRECT) and is supported on Linux 3.4 or newer only.
+	// The atomic write size of a packet-mode pipe is limited by PIPE_BUF (4096 bytes): longer writes are split
+	// into chunks of 4096 bytes (and a shorter tail), and those of concurrent writers may interleave.
+	// The writes made through (*Capturing).Writer keep their boundaries regardless of the size.
+	WriteBoundaries bool
+
+	// Ordered makes the capture keep the exact order of the output across all the output files,
+	// so the chunks come in the order the writes were made, e.g. the interleaving of STDOUT and STDERR is exact.
+	// Without it, every output file gets its own pipe, and the order of the chunks from different files
+	// is only very close to the order of the output.
+	//
+	// The output files write to the Unix datagram sockets connected to a single receiving socket,
+	// so every write becomes a separate chunk (like with WriteBoundaries). A write can't be larger than
+	// the socket send buffer (about 200 KiB by default, see /proc/sys/net/core/wmem_default), larger writes fail
+	// with EMSGSIZE. Every write costs a bit more than writing to a pipe, and PipeSize is ignored.
+	//
+	// With RedirectFDs, the restore doesn't wait for the child processes keeping the redirected descriptors open,
+	// and their output written after the restore is lost.
+	//
+	// It's supported on Linux only.
+	Ordered bool
+
+	// Redactors mask the secrets (tokens, passwords, emails, etc.) in the captured output before it's stored
+	// or passed through. See NewRegexpRedactor and NewLiteralRedactor for the built-in redactors.
+	//
+	// The output of every file is redacted as a stream, so the secrets split between the chunks get masked too.
+	// For that, the last MaxMatchLen-1 bytes of the output of every file are held back until more output comes
+	// or the capture is restored, so the chunks don't follow the writes, and the output passed through is delayed.
+	// The crash output of the Go runtime (see CrashOutput) is not redacted.
+	Redactors []Redactor
+
+	// Filter drops the captured chunks it returns false for. It gets the chunks after the redaction.
+	Filter func(chunk ChunkFromFile) bool
+
+	// PassThroughLines is the rule for passing through the lines of the captured output right away
+	// while the rest of the output stays captured, e.g. LinesMatching(regexp.MustCompile(`^(panic:|FAIL|WARN)`)).
+	// It gets the name of the output file and the line including the trailing newline. The matching lines are
+	// passed through as soon as they are complete and still get stored in the captured chunks. An incomplete line
+	// at the end of the output is checked when the capture is restored.
+	//
+	// Restoring with passThroughOuts set passes through the rest of the output only,
+	// so the lines are not duplicated.
+	PassThroughLines func(name string, line []byte) bool
+
+	// Sink receives the captured chunks instead of the capture keeping them in memory, so long-running captures
+	// don't grow the memory, e.g. a RotatingFileSink. The sink is flushed when the capture is restored.
+	//
+	// With a sink, the restore returns no chunks, and passes through only the output captured after
+	// the restore started. PassThroughLines can't be used with a sink.
+	Sink Sink
+
+	// Compress makes the capture store the captured output compressed with flate, so large verbose output
+	// takes much less memory. The output is compressed in blocks of 256 KiB shared by the chunks.
+	//
+	// The Chunk field of the compressed chunks is nil, their data is available via ChunkFromFile.Bytes,
+	// which decompresses the block of the chunk on every call. The Result methods and Replay decompress
+	// every block once. The output passed through, the Filter and the Sink get the uncompressed chunks.
+	Compress bool
+}
+
+// Capturing is an active capture started by Start.
+type Capturing struct {
+	capture *capture
+}
+
+// Start captures the output to the given targets according to the options and returns the capture.
+//
+// The list of targets must not be empty, must not contain nil files and must not contain duplicated files.
+// Targets with an empty Name get the default name of their files (see Target).
+// The explicit names must not be duplicated, the default ones are made unique (see Target).
+//
+// Start(Options{}, targets...).Restore works exactly like CaptureTargets(targets...).
+// See Capture for more information.
+func Start(opts Options, targets ...Target) *Capturing {
+	outFiles := targetFiles(targets)
+
+	mustNotBeEmpty(outFiles)
+	mustNotContainNils(outFiles)
+	mustNotContainDuplicates(outFiles)
+	mustBeConsistent(opts)
+
+	return watchForLeak(startCapture(opts, namedTargets(targets), false))
+}
+
+// Restore stops capturing, restores the output files and returns the captured output.
+// See RestoreFunc.
+func (c *Capturing) Restore(passThroughOuts bool) Result {
+	return c.capture.restore(passThroughOuts, nil, nil)
+}
+
+// RestoreIf stops capturing, restores the output files and returns the captured output like Restore does,
+// but decides whether to pass the captured output through after seeing it: the output is written
+// to the original output files in the order it was captured if passThrough returns true for it, e.g.
+//
+//	capturing.RestoreIf(func(result flowmingo.Result) bool {
+//		return t.Failed() || strings.Contains(result.Stderr(), "ERROR")
+//	})
+//
+// Unlike with Restore(true), the output is written after the output files are restored, so the output
+// written to them concurrently by other goroutines during the restore may come before the captured output.
+// passThrough must not start or restore captures.
+func (c *Capturing) RestoreIf(passThrough func(result Result) bool) Result {
+	return c.capture.restore(false, passThrough, nil)
+}
+
+// RestoreTo stops capturing, restores the output files and returns the captured output like Restore(true) does,
+// but passes the captured output through to the writers from dests by the names of the targets
+// instead of the original output files, e.g. to a log file or to t.Log. The output of the targets
+// missing in dests is not passed through. The errors of the writers are ignored.
+func (c *Capturing) RestoreTo(dests map[string]io.Writer) Result {
+	if dests == nil {
+		dests = map[string]io.Writer{}
+	}
+
+	return c.capture.restore(true, nil, dests)
+}
+
+// Info returns the description of the capture. The description stays available after restoring.
+func (c *Capturing) Info() CaptureInfo {
+	captureLock.Lock()
+	defer captureLock.Unlock()
+
+	return c.capture.info()
+}
+
+func mustBeConsistent(opts Options) {
+	mustBeValidRedactors(opts.Redactors)
+
+	if opts.Sink != nil && opts.PassThroughLines != nil {
+		panic("PassThroughLines can't be used with Sink")
+	}
+}
+
+func mustBeSupported(opts Options) {
+	if opts.RedirectFDs && !redirectSupported {
+		panic("RedirectFDs is supported on Linux, macOS and BSDs only")
+	}
+
+	if opts.CrashOutput != nil && !crashOutputSupported {
+		panic("CrashOutput requires Go 1.23 or newer")
+	}
+
+	if opts.WriteBoundaries && !packetPipesSupported {
+		panic("WriteBoundaries is supported on Linux only")
+	}
+
+	if opts.Ordered && !orderedSupported {
+		panic("Ordered is supported on Linux only")
+	}
+}
+
+// mustBeRelinkable panics if the capture can't be restored because of the captures stacked on top of it.
+func mustBeRelinkable(c *capture) {
+	for outFileNumber, outFile := range c.outFiles {
+		above := captureAbove(c, outFile)
+		if above == nil || above.redirections[above.fileNumber(outFile)] == nil {
+			continue
+		}
+
+		panic(fmt.Sprintf("cannot restore capture #%d (created at %s) out of order: "+
+			"capture #%d (created at %s) stacked on top of it redirects the file descriptor of output file %q, "+
+			"so it must be restored first",
+			c.id, c.createdAt, above.id, above.createdAt, c.targets[outFileNumber].Name))
+	}
+}
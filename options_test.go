@@ -0,0 +1,81 @@
+package flowmingo_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestStart_CapturesLikeCaptureTargets(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout, Name: "out"})
+	_, _ = os.Stdout.WriteString("captured")
+
+	info := capturing.Info()
+	result := capturing.Restore(false)
+
+	assertEqualStrings(t, "captured", string(result.ByName("out").Combined()))
+	assertEqualInts(t, 1, len(info.Targets))
+	assertEqualStrings(t, "out", info.Targets[0].Name)
+	assertEqualInts(t, int(info.ID), int(capturing.Info().ID))
+	assertPanics(t, func() { capturing.Restore(false) })
+}
+
+func TestStart_Nil(t *testing.T) {
+	assertPanics(t, func() { flowmingo.Start(flowmingo.Options{}, flowmingo.Target{}) })
+}
+
+func TestStart_Empty(t *testing.T) {
+	assertPanics(t, func() { flowmingo.Start(flowmingo.Options{}) })
+}
+
+func TestCapturing_RestoreIf(t *testing.T) {
+	for _, passThrough := range []bool{true, false} {
+		outer := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout}, flowmingo.Target{File: os.Stderr})
+
+		inner := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout}, flowmingo.Target{File: os.Stderr})
+		_, _ = os.Stdout.WriteString("abc")
+		_, _ = os.Stderr.WriteString("ERROR")
+
+		var seen flowmingo.Result
+
+		innerResult := inner.RestoreIf(func(result flowmingo.Result) bool {
+			seen = result
+
+			return passThrough
+		})
+
+		outerResult := outer.Restore(false)
+
+		assertEqualStrings(t, "abc", innerResult.Stdout())
+		assertEqualStrings(t, "ERROR", innerResult.Stderr())
+		assertEqualInts(t, len(innerResult), len(seen))
+
+		if passThrough {
+			assertEqualStrings(t, "abc", outerResult.Stdout())
+			assertEqualStrings(t, "ERROR", outerResult.Stderr())
+		} else {
+			assertEqualInts(t, 0, len(outerResult))
+		}
+	}
+}
+
+func TestCapturing_RestoreTo(t *testing.T) {
+	outer := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout}, flowmingo.Target{File: os.Stderr})
+
+	inner := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout}, flowmingo.Target{File: os.Stderr})
+	_, _ = os.Stdout.WriteString("abc")
+	_, _ = os.Stderr.WriteString("def")
+
+	var buf bytes.Buffer
+
+	innerResult := inner.RestoreTo(map[string]io.Writer{flowmingo.StdoutName: &buf})
+	outerResult := outer.Restore(false)
+
+	assertEqualStrings(t, "abc", innerResult.Stdout())
+	assertEqualStrings(t, "def", innerResult.Stderr())
+	assertEqualStrings(t, "abc", buf.String())
+	assertEqualInts(t, 0, len(outerResult))
+}
@@ -0,0 +1,64 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package flowmingo_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestStart_RedirectFDsCapturesWritesToDescriptor(t *testing.T) {
+	for _, passThrough := range []bool{true, false} {
+		reader, writer, err := os.Pipe()
+		assertNoError(t, err)
+
+		fd := int(writer.Fd())
+
+		capturing := flowmingo.Start(flowmingo.Options{RedirectFDs: true}, flowmingo.Target{File: writer, Name: "fd"})
+		_, _ = syscall.Write(fd, []byte("raw "))
+		_, _ = writer.WriteString("file")
+
+		result := capturing.Restore(passThrough)
+
+		_, _ = writer.WriteString(" after")
+		_ = writer.Close()
+
+		var buf bytes.Buffer
+		_, err = io.Copy(&buf, reader)
+		assertNoError(t, err)
+		_ = reader.Close()
+
+		expected := " after"
+		if passThrough {
+			expected = "raw file after"
+		}
+
+		assertEqualStrings(t, "raw file", string(result.ByName("fd").Combined()))
+		assertEqualStrings(t, expected, buf.String())
+	}
+}
+
+func TestStart_RedirectFDsCapturesRuntimeStderr(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{RedirectFDs: true}, flowmingo.Target{File: os.Stderr})
+	println("written by the runtime")
+
+	result := capturing.Restore(false)
+
+	assertEqualStrings(t, "written by the runtime\n", result.Stderr())
+}
+
+func TestStart_RedirectFDsMustNotBeRelinkedOutOfOrder(t *testing.T) {
+	restore := flowmingo.Capture(os.Stderr)
+	capturing := flowmingo.Start(flowmingo.Options{RedirectFDs: true}, flowmingo.Target{File: os.Stderr})
+
+	assertPanics(t, func() { restore(false) })
+
+	capturing.Restore(false)
+	restore(false)
+}
@@ -0,0 +1,170 @@
+package flowmingo
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+const orderedSupported = true
+
+// orderedReceiver is the receiving end of the ordered capture: a datagram socket
+// all the output files send their output to. The kernel queues the datagrams in the order
+// they were sent, and the receiver tells the output files apart by the addresses of their sockets.
+type orderedReceiver struct {
+	fd          int
+	name        string
+	controlFd   int    // the socket sending the datagram that stops the receiver
+	controlName string // the address of controlFd
+	maxDatagram int
+	targets     []Target
+	senders     map[string]int // the numbers of the targets by the addresses of the sending sockets
+}
+
+// newDatagramSocket creates a Unix datagram socket bound to an automatically chosen abstract address.
+func newDatagramSocket() (fd int, name string, err error) {
+	fd, err = syscall.Socket(syscall.AF_UNIX, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return -1, "", os.NewSyscallError("socket", err)
+	}
+
+	// binding to an empty address makes the kernel choose a unique abstract address
+	if err = syscall.Bind(fd, &syscall.SockaddrUnix{}); err != nil {
+		_ = syscall.Close(fd)
+
+		return -1, "", os.NewSyscallError("bind", err)
+	}
+
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		_ = syscall.Close(fd)
+
+		return -1, "", os.NewSyscallError("getsockname", err)
+	}
+
+	return fd, sockaddrName(sa), nil
+}
+
+func sockaddrName(sa syscall.Sockaddr) string {
+	if unixSa, ok := sa.(*syscall.SockaddrUnix); ok {
+		return unixSa.Name
+	}
+
+	return ""
+}
+
+func newOrderedReceiver(targets []Target) (*orderedReceiver, error) {
+	fd, name, err := newDatagramSocket()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &orderedReceiver{fd: fd, name: name, targets: targets, senders: make(map[string]int, len(targets))}
+
+	r.controlFd, r.controlName, err = r.newSenderSocket()
+	if err != nil {
+		_ = syscall.Close(fd)
+
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *orderedReceiver) newSenderSocket() (fd int, name string, err error) {
+	fd, name, err = newDatagramSocket()
+	if err != nil {
+		return -1, "", err
+	}
+
+	if err = syscall.Connect(fd, &syscall.SockaddrUnix{Name: r.name}); err != nil {
+		_ = syscall.Close(fd)
+
+		return -1, "", os.NewSyscallError("connect", err)
+	}
+
+	// a datagram can't be larger than the send buffer
+	if sndBuf, err := syscall.GetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_SNDBUF); err == nil &&
+		sndBuf > r.maxDatagram {
+		r.maxDatagram = sndBuf
+	}
+
+	return fd, name, nil
+}
+
+// newSender creates the blocking socket to write the output of the target to.
+func (r *orderedReceiver) newSender(targetNumber int) (*os.File, error) {
+	fd, name, err := r.newSenderSocket()
+	if err != nil {
+		return nil, err
+	}
+
+	r.senders[name] = targetNumber
+
+	return os.NewFile(uintptr(fd), "|1"), nil
+}
+
+// read receives the datagrams until the stop datagram comes. Every datagram becomes a chunk.
+func (r *orderedReceiver) read(outC chan<- ChunkFromFile, finishCh chan<- bool, stats *captureStats) {
+	buf := make([]byte, r.maxDatagram)
+	blockSize := firstChunkBlockSize
+
+	var block []byte
+
+	for {
+		n, from, err := syscall.Recvfrom(r.fd, buf, 0)
+		if err == syscall.EINTR {
+			continue
+		}
+
+		if err != nil {
+			break
+		}
+
+		name := sockaddrName(from)
+		if name == r.controlName {
+			break
+		}
+
+		targetNumber, ok := r.senders[name]
+		if !ok || n == 0 {
+			continue
+		}
+
+		target := r.targets[targetNumber]
+
+		if len(block) < n {
+			for blockSize < n {
+				blockSize *= 2
+			}
+
+			block = make([]byte, blockSize)
+
+			if blockSize < 4*r.maxDatagram {
+				blockSize *= 2
+			}
+		}
+
+		chunk := ChunkFromFile{Chunk: block[:n:n], OutFile: target.File, Name: target.Name, Time: time.Now()}
+		copy(chunk.Chunk, buf[:n])
+		block = block[n:]
+
+		if stats == nil {
+			outC <- chunk
+		} else {
+			sendStart := time.Now()
+			outC <- chunk
+			stats.addChunk(n, 0, time.Since(sendStart)) // there is no pipe backlog to measure
+		}
+	}
+
+	_ = syscall.Close(r.fd)
+	_ = syscall.Close(r.controlFd)
+
+	finishCh <- true
+}
+
+// stop makes the receiver finish after reading all the datagrams sent before.
+func (r *orderedReceiver) stop() {
+	_, _ = syscall.Write(r.controlFd, nil)
+}
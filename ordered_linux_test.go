@@ -0,0 +1,75 @@
+package flowmingo_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestStart_Ordered(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{Ordered: true},
+		flowmingo.Target{File: os.Stdout}, flowmingo.Target{File: os.Stderr})
+
+	for i := 0; i < 1000; i++ {
+		_, _ = os.Stdout.WriteString(strconv.Itoa(2 * i))
+		_, _ = os.Stderr.WriteString(strconv.Itoa(2*i + 1))
+	}
+
+	result := capturing.Restore(false)
+
+	assertEqualInts(t, 2000, len(result))
+
+	for i, chunk := range result {
+		expectedFile := os.Stdout
+		if i%2 == 1 {
+			expectedFile = os.Stderr
+		}
+
+		assertEqualFiles(t, expectedFile, chunk.OutFile)
+		assertEqualStrings(t, strconv.Itoa(i), string(chunk.Chunk))
+	}
+}
+
+func TestStart_OrderedWithRedirectFDs(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{Ordered: true, RedirectFDs: true},
+		flowmingo.Target{File: os.Stdout}, flowmingo.Target{File: os.Stderr})
+	_, _ = syscall.Write(int(os.Stderr.Fd()), []byte("a"))
+	_, _ = syscall.Write(int(os.Stdout.Fd()), []byte("b"))
+	_, _ = syscall.Write(int(os.Stderr.Fd()), []byte("c"))
+	result := capturing.Restore(false)
+
+	assertEqualInts(t, 3, len(result))
+	assertEqualStrings(t, "a", string(result[0].Chunk))
+	assertEqualFiles(t, os.Stderr, result[0].OutFile)
+	assertEqualStrings(t, "b", string(result[1].Chunk))
+	assertEqualFiles(t, os.Stdout, result[1].OutFile)
+	assertEqualStrings(t, "c", string(result[2].Chunk))
+	assertEqualFiles(t, os.Stderr, result[2].OutFile)
+}
+
+func TestStart_OrderedFailsOnTooLargeWrites(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{Ordered: true}, flowmingo.Target{File: os.Stdout})
+	_, err := os.Stdout.Write(bytes.Repeat([]byte{'x'}, 16<<20))
+	_, _ = os.Stdout.WriteString("abc")
+	result := capturing.Restore(false)
+
+	assertTrue(t, err != nil, "Expected the too large write to fail")
+	assertEqualStrings(t, "abc", result.Stdout())
+}
+
+func TestStartCmd_Ordered(t *testing.T) {
+	cmd := exec.Command("sh", "-c", "echo 1; echo 2 >&2; echo 3 >&3; echo 4")
+	capturing := flowmingo.StartCmd(flowmingo.Options{Ordered: true}, cmd, "diag")
+
+	assertNoError(t, cmd.Run())
+
+	result := capturing.Restore(false)
+
+	assertEqualStrings(t, "1\n2\n3\n4\n", string(result.Combined()))
+	assertEqualStrings(t, "diag", result[2].Name)
+}
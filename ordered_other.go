@@ -0,0 +1,27 @@
+//go:build !linux
+// +build !linux
+
+package flowmingo
+
+import (
+	"errors"
+	"os"
+)
+
+const orderedSupported = false
+
+var errOrderedNotSupported = errors.New("ordered capturing is not supported on this platform")
+
+type orderedReceiver struct{}
+
+func newOrderedReceiver([]Target) (*orderedReceiver, error) {
+	return nil, errOrderedNotSupported
+}
+
+func (r *orderedReceiver) newSender(int) (*os.File, error) {
+	return nil, errOrderedNotSupported
+}
+
+func (r *orderedReceiver) read(chan<- ChunkFromFile, chan<- bool, *captureStats) {}
+
+func (r *orderedReceiver) stop() {}
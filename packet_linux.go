@@ -0,0 +1,20 @@
+package flowmingo
+
+import (
+	"os"
+	"syscall"
+)
+
+const packetPipesSupported = true
+
+// newPacketPipe creates a blocking pipe in the packet mode (O_DIRECT), so every write to the pipe
+// up to PIPE_BUF (4096) bytes long is read back in one piece by a single read. It requires Linux 3.4 or newer.
+func newPacketPipe() (outR, outW *os.File, outWFd int, err error) {
+	var fds [2]int
+
+	if err = syscall.Pipe2(fds[:], syscall.O_CLOEXEC|syscall.O_DIRECT); err != nil {
+		return nil, nil, -1, os.NewSyscallError("pipe2", err)
+	}
+
+	return os.NewFile(uintptr(fds[0]), "|0"), os.NewFile(uintptr(fds[1]), "|1"), fds[1], nil
+}
@@ -0,0 +1,40 @@
+package flowmingo_test
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestStart_WriteBoundaries(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{WriteBoundaries: true}, flowmingo.Target{File: os.Stdout})
+	_, _ = os.Stdout.WriteString("a")
+	_, _ = os.Stdout.WriteString("bc")
+	_, _ = os.Stdout.WriteString("def")
+	_, _ = os.Stdout.WriteString(strings.Repeat("x", 10000))
+	result := capturing.Restore(false)
+
+	chunks := make([]string, 0, len(result))
+	for _, chunk := range result {
+		chunks = append(chunks, string(chunk.Chunk))
+	}
+
+	assertEqualStrings(t, strings.Join([]string{
+		"a", "bc", "def", strings.Repeat("x", 4096), strings.Repeat("x", 4096), strings.Repeat("x", 1808),
+	}, "|"), strings.Join(chunks, "|"))
+}
+
+func TestStart_WriteBoundariesWithRedirectFDs(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{WriteBoundaries: true, RedirectFDs: true},
+		flowmingo.Target{File: os.Stdout})
+	_, _ = syscall.Write(int(os.Stdout.Fd()), []byte("abc"))
+	_, _ = syscall.Write(int(os.Stdout.Fd()), []byte("de"))
+	result := capturing.Restore(false)
+
+	assertEqualInts(t, 2, len(result))
+	assertEqualStrings(t, "abc", string(result[0].Chunk))
+	assertEqualStrings(t, "de", string(result[1].Chunk))
+}
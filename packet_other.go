@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package flowmingo
+
+import (
+	"errors"
+	"os"
+)
+
+const packetPipesSupported = false
+
+func newPacketPipe() (outR, outW *os.File, outWFd int, err error) {
+	return nil, nil, -1, errors.New("packet-mode pipes are not supported on this platform")
+}
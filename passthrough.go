@@ -0,0 +1,103 @@
+package flowmingo
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+)
+
+// LinesMatching returns a rule for Options.PassThroughLines passing through the lines
+// matching any of the regular expressions.
+func LinesMatching(patterns ...*regexp.Regexp) func(name string, line []byte) bool {
+	return func(_ string, line []byte) bool {
+		for _, pattern := range patterns {
+			if pattern.Match(line) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// lineSegment is a part of a line within a captured chunk.
+type lineSegment struct {
+	chunkIndex int
+	start, end int
+}
+
+// passThroughLines splits the just stored chunk into lines and passes through the lines matching the rule.
+// The incomplete line at the end of the chunk waits for the rest. It must be called with chunksFromPipesLock locked.
+func (c *capture) passThroughLines(chunkIndex int, chunk ChunkFromFile) {
+	segments := c.lineSegments[chunk.OutFile]
+
+	for start := 0; start < len(chunk.Chunk); {
+		end := bytes.IndexByte(chunk.Chunk[start:], '\n')
+		if end < 0 {
+			segments = append(segments, lineSegment{chunkIndex: chunkIndex, start: start, end: len(chunk.Chunk)})
+
+			break
+		}
+
+		end += start + 1
+		segments = append(segments, lineSegment{chunkIndex: chunkIndex, start: start, end: end})
+		c.passThroughLine(chunk.OutFile, chunk.Name, segments)
+		segments = segments[:0]
+		start = end
+	}
+
+	c.lineSegments[chunk.OutFile] = segments
+}
+
+// passThroughIncompleteLines passes through the lines without the trailing newline at the end of the output
+// if they match the rule. It must be called with chunksFromPipesLock locked.
+func (c *capture) passThroughIncompleteLines() {
+	for _, target := range c.targets {
+		if segments := c.lineSegments[target.File]; len(segments) > 0 {
+			c.passThroughLine(target.File, target.Name, segments)
+			delete(c.lineSegments, target.File)
+		}
+	}
+}
+
+func (c *capture) passThroughLine(outFile *os.File, name string, segments []lineSegment) {
+	var line []byte
+
+	if len(segments) == 1 {
+		line = c.chunksFromPipes[segments[0].chunkIndex].Bytes()[segments[0].start:segments[0].end]
+	} else {
+		for _, segment := range segments {
+			line = append(line, c.chunksFromPipes[segment.chunkIndex].Bytes()[segment.start:segment.end]...)
+		}
+	}
+
+	if !c.passThroughLineRule(name, line) {
+		return
+	}
+
+	if origOutPipe, ok := c.outFilesOrigMap[outFile]; ok {
+		_, _ = origOutPipe.Write(line)
+	}
+
+	for _, segment := range segments {
+		c.passedThrough[segment.chunkIndex] = append(c.passedThrough[segment.chunkIndex], [2]int{segment.start, segment.end})
+	}
+}
+
+// writeNotPassedThrough writes the parts of the chunk that haven't been passed through yet.
+func writeNotPassedThrough(dest io.Writer, chunk []byte, passedThrough [][2]int) {
+	start := 0
+
+	for _, passed := range passedThrough {
+		if passed[0] > start {
+			_, _ = dest.Write(chunk[start:passed[0]])
+		}
+
+		start = passed[1]
+	}
+
+	if start < len(chunk) {
+		_, _ = dest.Write(chunk[start:])
+	}
+}
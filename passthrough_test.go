@@ -0,0 +1,75 @@
+package flowmingo_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestStart_PassThroughLines(t *testing.T) {
+	outer := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+
+	inner := flowmingo.Start(flowmingo.Options{
+		PassThroughLines: flowmingo.LinesMatching(regexp.MustCompile(`^(WARN|FAIL)`)),
+	}, flowmingo.Target{File: os.Stdout})
+
+	stdout := inner.Writer(flowmingo.StdoutName)
+	_, _ = stdout.Write([]byte("noise\nWA"))
+	_, _ = stdout.Write([]byte("RN: split\nmore noise\n"))
+	_, _ = stdout.Write([]byte("FAIL"))
+	innerResult := inner.Restore(false)
+
+	outerResult := outer.Restore(false)
+
+	assertEqualStrings(t, "noise\nWARN: split\nmore noise\nFAIL", innerResult.Stdout())
+	assertEqualStrings(t, "WARN: split\nFAIL", outerResult.Stdout())
+}
+
+func TestStart_PassThroughLinesAreNotDuplicatedOnRestore(t *testing.T) {
+	outer := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+
+	inner := flowmingo.Start(flowmingo.Options{
+		PassThroughLines: flowmingo.LinesMatching(regexp.MustCompile(`^WARN`)),
+	}, flowmingo.Target{File: os.Stdout})
+
+	stdout := inner.Writer(flowmingo.StdoutName)
+	_, _ = stdout.Write([]byte("a\nWARN: 1\nb\n"))
+	_, _ = stdout.Write([]byte("WARN: 2\nc"))
+	inner.Restore(true)
+
+	outerResult := outer.Restore(false)
+
+	// the lines passed through come first, but the chunks stored after the restore started
+	// are passed through as they come, so only the absence of duplicates is guaranteed
+	output := outerResult.Stdout()
+	assertEqualInts(t, len("WARN: 1\nWARN: 2\na\nb\nc"), len(output))
+
+	for _, line := range []string{"WARN: 1\n", "WARN: 2\n", "a\n", "b\n"} {
+		assertEqualInts(t, 1, strings.Count(output, line))
+	}
+}
+
+func TestStart_PassThroughLinesWithRestoreTo(t *testing.T) {
+	outer := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+
+	inner := flowmingo.Start(flowmingo.Options{
+		PassThroughLines: flowmingo.LinesMatching(regexp.MustCompile(`^WARN`)),
+	}, flowmingo.Target{File: os.Stdout})
+
+	_, _ = inner.Writer(flowmingo.StdoutName).Write([]byte("a\nWARN b\nc\n"))
+
+	var buf bytes.Buffer
+
+	inner.RestoreTo(map[string]io.Writer{flowmingo.StdoutName: &buf})
+
+	outerResult := outer.Restore(false)
+
+	// the lines passed through to the original file are not dropped from the destination
+	assertEqualStrings(t, "a\nWARN b\nc\n", buf.String())
+	assertEqualStrings(t, "WARN b\n", outerResult.Stdout())
+}
@@ -0,0 +1,99 @@
+//go:build go1.12
+// +build go1.12
+
+package flowmingo
+
+import (
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	fSetPipeSz = 0x407 // F_SETPIPE_SZ
+	fGetPipeSz = 0x408 // F_GETPIPE_SZ
+)
+
+// setPipeCapacity enlarges the pipe to hold at least size bytes if size is positive
+// and returns the actual capacity of the pipe. If the size exceeds the limit for unprivileged users,
+// the pipe is enlarged to the limit.
+func setPipeCapacity(pipe *os.File, size int) (int, error) {
+	rawConn, err := pipe.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	capacity := 0
+
+	var fcntlErr error
+
+	err = rawConn.Control(func(fd uintptr) {
+		if size > 0 {
+			fcntlErr = fcntlSetPipeSize(fd, size)
+			if fcntlErr == syscall.EPERM {
+				if maxSize, maxErr := pipeMaxSize(); maxErr == nil && maxSize < size {
+					fcntlErr = fcntlSetPipeSize(fd, maxSize)
+				}
+			}
+		}
+
+		r, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, fGetPipeSz, 0)
+		if errno != 0 {
+			fcntlErr = errno
+
+			return
+		}
+
+		capacity = int(r)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if fcntlErr != nil {
+		return capacity, os.NewSyscallError("fcntl", fcntlErr)
+	}
+
+	return capacity, nil
+}
+
+func fcntlSetPipeSize(fd uintptr, size int) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, fSetPipeSz, uintptr(size)); errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func pipeMaxSize() (int, error) {
+	contents, err := ioutil.ReadFile("/proc/sys/fs/pipe-max-size")
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(contents)))
+}
+
+// pipeBacklog returns the number of bytes waiting in the pipe and whether it could be determined.
+func pipeBacklog(pipe *os.File) (int, bool) {
+	rawConn, err := pipe.SyscallConn()
+	if err != nil {
+		return 0, false
+	}
+
+	var backlog int32
+
+	var errno syscall.Errno
+
+	if err = rawConn.Control(func(fd uintptr) {
+		//nolint:gosec // ioctl(fd, FIONREAD, &backlog)
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, syscall.TIOCINQ, uintptr(unsafe.Pointer(&backlog)))
+	}); err != nil || errno != 0 {
+		return 0, false
+	}
+
+	return int(backlog), true
+}
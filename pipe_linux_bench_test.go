@@ -0,0 +1,59 @@
+//go:build go1.13
+// +build go1.13
+
+package flowmingo_test
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/zenovich/flowmingo"
+)
+
+// benchmarkBurstLatency measures how long the writer blocks on bursts of output
+// and reports the tail latency of the bursts.
+func benchmarkBurstLatency(b *testing.B, pipeSize int) {
+	outFile, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	defer func() { _ = outFile.Close() }()
+
+	const burstSize = 512 << 10
+
+	burst := bytes.Repeat([]byte{'x'}, burstSize)
+	latencies := make([]time.Duration, 0, b.N)
+
+	b.SetBytes(burstSize)
+	b.ResetTimer()
+
+	capturing := flowmingo.Start(flowmingo.Options{PipeSize: pipeSize, Filter: discard}, flowmingo.Target{File: outFile})
+
+	for i := 0; i < b.N; i++ {
+		start := time.Now()
+		_, _ = outFile.Write(burst)
+		latencies = append(latencies, time.Since(start))
+
+		// let the capture drain the pipe between the bursts
+		time.Sleep(time.Millisecond)
+	}
+
+	capturing.Restore(false)
+	b.StopTimer()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	b.ReportMetric(float64(latencies[len(latencies)*50/100].Nanoseconds()), "p50-ns/burst")
+	b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns/burst")
+}
+
+func BenchmarkCapture_BurstDefaultPipe(b *testing.B) {
+	benchmarkBurstLatency(b, 0)
+}
+
+func BenchmarkCapture_BurstLargePipe(b *testing.B) {
+	benchmarkBurstLatency(b, 1<<20)
+}
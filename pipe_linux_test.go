@@ -0,0 +1,37 @@
+//go:build go1.12
+// +build go1.12
+
+package flowmingo_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestStart_ReportsPipeCapacity(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+	defaultCapacity := capturing.Info().Targets[0].PipeCapacity
+	capturing.Restore(false)
+
+	assertTrue(t, defaultCapacity > 0, "Expected the default capacity to be reported")
+
+	capturing = flowmingo.Start(flowmingo.Options{PipeSize: 256 << 10}, flowmingo.Target{File: os.Stdout})
+	_, _ = os.Stdout.Write(bytes.Repeat([]byte{'x'}, 200<<10))
+	capacity := capturing.Info().Targets[0].PipeCapacity
+	result := capturing.Restore(false)
+
+	assertTrue(t, capacity >= 256<<10, "Expected the pipe to be enlarged")
+	assertEqualStrings(t, strings.Repeat("x", 200<<10), result.Stdout())
+}
+
+func TestStart_CapsPipeSizeAtLimit(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{PipeSize: 1 << 30}, flowmingo.Target{File: os.Stdout})
+	capacity := capturing.Info().Targets[0].PipeCapacity
+	capturing.Restore(false)
+
+	assertTrue(t, capacity >= 64<<10 && capacity <= 1<<30, "Unexpected pipe capacity")
+}
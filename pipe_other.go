@@ -0,0 +1,16 @@
+//go:build !linux || !go1.12
+// +build !linux !go1.12
+
+package flowmingo
+
+import "os"
+
+// setPipeCapacity can't change or query the capacity of the pipe on this platform.
+func setPipeCapacity(*os.File, int) (int, error) {
+	return 0, nil
+}
+
+// pipeBacklog can't determine the number of bytes waiting in the pipe on this platform.
+func pipeBacklog(*os.File) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,208 @@
+package flowmingo
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Redactor finds the secrets to mask in the captured output (see Options.Redactors).
+type Redactor interface {
+	// FindAllIndex returns the start and end indices of the secrets found in data
+	// like (*regexp.Regexp).FindAllIndex(data, -1) does.
+	FindAllIndex(data []byte) [][]int
+	// MaxMatchLen returns the maximum length of a secret. The output is searched for the secrets
+	// across the chunk boundaries within this length only.
+	MaxMatchLen() int
+	// Mask returns the replacement for the secret.
+	Mask(secret []byte) []byte
+}
+
+type regexpRedactor struct {
+	re          *regexp.Regexp
+	maxMatchLen int
+	mask        []byte
+}
+
+// NewRegexpRedactor returns a redactor replacing the matches of the regular expression with the mask.
+// The matches longer than maxMatchLen bytes may be missed when they are split between the chunks,
+// so the regular expression should limit the length of its matches, e.g. `token=\w{1,64}`.
+// NewRegexpRedactor panics if maxMatchLen is not positive.
+func NewRegexpRedactor(re *regexp.Regexp, maxMatchLen int, mask string) Redactor {
+	if maxMatchLen < 1 {
+		panic("maxMatchLen must be positive")
+	}
+
+	return &regexpRedactor{re: re, maxMatchLen: maxMatchLen, mask: []byte(mask)}
+}
+
+func (r *regexpRedactor) FindAllIndex(data []byte) [][]int {
+	return r.re.FindAllIndex(data, -1)
+}
+
+func (r *regexpRedactor) MaxMatchLen() int {
+	return r.maxMatchLen
+}
+
+func (r *regexpRedactor) Mask([]byte) []byte {
+	return r.mask
+}
+
+// NewLiteralRedactor returns a redactor replacing the given secrets with the mask.
+// The longest secret wins when several secrets start at the same position. Empty secrets are ignored.
+func NewLiteralRedactor(mask string, secrets ...string) Redactor {
+	sorted := make([]string, 0, len(secrets))
+
+	for _, secret := range secrets {
+		if secret != "" {
+			sorted = append(sorted, secret)
+		}
+	}
+
+	if len(sorted) == 0 {
+		return &regexpRedactor{re: regexp.MustCompile(`$^`), maxMatchLen: 1, mask: []byte(mask)}
+	}
+
+	// the leftmost alternative wins in Go regular expressions, so the longer secrets go first
+	sort.Sort(byLengthDesc(sorted))
+
+	quoted := make([]string, len(sorted))
+	for i, secret := range sorted {
+		quoted[i] = regexp.QuoteMeta(secret)
+	}
+
+	return NewRegexpRedactor(regexp.MustCompile(strings.Join(quoted, "|")), len(sorted[0]), mask)
+}
+
+type byLengthDesc []string
+
+func (s byLengthDesc) Len() int           { return len(s) }
+func (s byLengthDesc) Less(i, j int) bool { return len(s[i]) > len(s[j]) }
+func (s byLengthDesc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// redaction applies the redactors to the output of every output file as a stream.
+// The last holdBack bytes of the output of every file are held back until more output
+// comes, since they may contain the beginning of a secret.
+type redaction struct {
+	redactors []Redactor
+	targets   []Target
+	holdBack  int
+	pending   map[*os.File][]byte
+}
+
+func newRedaction(redactors []Redactor, targets []Target) *redaction {
+	r := &redaction{redactors: redactors, targets: targets, pending: make(map[*os.File][]byte, len(targets))}
+
+	for _, redactor := range redactors {
+		if redactor.MaxMatchLen()-1 > r.holdBack {
+			r.holdBack = redactor.MaxMatchLen() - 1
+		}
+	}
+
+	return r
+}
+
+// redact returns the chunk with the redacted output that is safe to release, which may be empty.
+func (r *redaction) redact(chunk ChunkFromFile) ChunkFromFile {
+	data := chunk.Chunk
+	if pending := r.pending[chunk.OutFile]; len(pending) > 0 {
+		data = append(pending, data...)
+	}
+
+	var rest []byte
+
+	chunk.Chunk, rest = r.apply(data, len(data)-r.holdBack)
+	r.pending[chunk.OutFile] = append([]byte(nil), rest...)
+
+	return chunk
+}
+
+// flush returns the chunks with the redacted output held back.
+func (r *redaction) flush() []ChunkFromFile {
+	var chunks []ChunkFromFile
+
+	for _, target := range r.targets {
+		pending := r.pending[target.File]
+		if len(pending) == 0 {
+			continue
+		}
+
+		redacted, _ := r.apply(pending, len(pending))
+		chunks = append(chunks, ChunkFromFile{Chunk: redacted, OutFile: target.File, Name: target.Name, Time: time.Now()})
+		delete(r.pending, target.File)
+	}
+
+	return chunks
+}
+
+// apply masks the secrets starting before the cut and returns the redacted output
+// and the rest of the data that may contain the beginning of a secret.
+func (r *redaction) apply(data []byte, cut int) (redacted, rest []byte) {
+	if cut < 0 {
+		cut = 0
+	}
+
+	var matches []match
+
+	for _, redactor := range r.redactors {
+		for _, loc := range redactor.FindAllIndex(data) {
+			if loc[0] < cut && loc[1] > loc[0] {
+				matches = append(matches, match{start: loc[0], end: loc[1], redactor: redactor})
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		return data[:cut], data[cut:]
+	}
+
+	sort.Sort(byStart(matches))
+
+	end := 0
+
+	for _, m := range matches {
+		if m.start < end {
+			continue // overlaps the previous secret
+		}
+
+		redacted = append(redacted, data[end:m.start]...)
+		redacted = append(redacted, m.redactor.Mask(data[m.start:m.end])...)
+		end = m.end
+	}
+
+	if end < cut {
+		redacted = append(redacted, data[end:cut]...)
+		end = cut
+	}
+
+	return redacted, data[end:]
+}
+
+type match struct {
+	start, end int
+	redactor   Redactor
+}
+
+// byStart sorts the matches by the start, the longer ones first.
+type byStart []match
+
+func (s byStart) Len() int      { return len(s) }
+func (s byStart) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byStart) Less(i, j int) bool {
+	if s[i].start != s[j].start {
+		return s[i].start < s[j].start
+	}
+
+	return s[i].end > s[j].end
+}
+
+func mustBeValidRedactors(redactors []Redactor) {
+	for i, redactor := range redactors {
+		if redactor == nil {
+			panic(fmt.Sprintf("redactor #%d is nil", i))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package flowmingo_test
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestStart_RedactsSecretsSplitBetweenChunks(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{
+		Redactors: []flowmingo.Redactor{flowmingo.NewRegexpRedactor(regexp.MustCompile(`token=\w{1,16}`), 22, "token=***")},
+	}, flowmingo.Target{File: os.Stdout}, flowmingo.Target{File: os.Stderr})
+
+	// the writes through the writer keep their boundaries
+	stdout := capturing.Writer(flowmingo.StdoutName)
+	_, _ = stdout.Write([]byte("a tok"))
+	_, _ = stdout.Write([]byte("en=abc"))
+	_, _ = stdout.Write([]byte("def and token=xyz\n"))
+	_, _ = os.Stderr.WriteString("token=qwerty")
+	result := capturing.Restore(false)
+
+	assertEqualStrings(t, "a token=*** and token=***\n", result.Stdout())
+	assertEqualStrings(t, "token=***", result.Stderr())
+}
+
+func TestStart_RedactsPassedThroughOutput(t *testing.T) {
+	outer := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+
+	inner := flowmingo.Start(flowmingo.Options{
+		Redactors: []flowmingo.Redactor{flowmingo.NewLiteralRedactor("[hidden]", "secret", "secret-password")},
+	}, flowmingo.Target{File: os.Stdout})
+	_, _ = os.Stdout.WriteString("my secret-password, your secret")
+	innerResult := inner.Restore(true)
+
+	outerResult := outer.Restore(false)
+
+	assertEqualStrings(t, "my [hidden], your [hidden]", innerResult.Stdout())
+	assertEqualStrings(t, "my [hidden], your [hidden]", outerResult.Stdout())
+}
+
+func TestStart_Filter(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{
+		Filter: func(chunk flowmingo.ChunkFromFile) bool {
+			return !bytes.HasPrefix(chunk.Chunk, []byte("debug:"))
+		},
+	}, flowmingo.Target{File: os.Stdout})
+
+	stdout := capturing.Writer(flowmingo.StdoutName)
+	_, _ = stdout.Write([]byte("debug: noise\n"))
+	_, _ = stdout.Write([]byte("result\n"))
+	result := capturing.Restore(false)
+
+	assertEqualStrings(t, "result\n", result.Stdout())
+}
+
+func TestStart_PanicsOnNilRedactor(t *testing.T) {
+	assertPanics(t, func() {
+		flowmingo.Start(flowmingo.Options{Redactors: []flowmingo.Redactor{nil}}, flowmingo.Target{File: os.Stdout})
+	})
+}
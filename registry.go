@@ -0,0 +1,173 @@
+package flowmingo
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+const packagePath = "github.com/zenovich/flowmingo"
+
+// The registry of active captures is guarded by captureLock.
+var (
+	lastCaptureID  int64
+	activeCaptures []*capture                  // in the order of creation
+	captureStacks  = map[*os.File][]*capture{} // per output file, from the bottom to the top
+)
+
+// CaptureInfo describes an active capture, i.e. a capture whose restore function hasn't been called yet.
+type CaptureInfo struct {
+	// ID identifies the capture within the process. IDs are assigned in the order of creation starting from 1.
+	ID int64
+	// CreatedAt is the "file:line" location of the code that started the capture.
+	CreatedAt string
+	// Stack is the stack trace of the goroutine that started the capture at the moment of starting.
+	Stack string
+	// Targets are the captured output files in the order they were given to the capture.
+	Targets []TargetInfo
+}
+
+// TargetInfo describes an output file held by an active capture.
+type TargetInfo struct {
+	Name string
+	File *os.File
+	// Depth is the position of the capture in the stack of the captures of the file.
+	// Zero means the capture is at the bottom, i.e. it replaced the original file. It's -1 for restored captures.
+	Depth int
+	// Top tells whether the capture is at the top of the stack, i.e. the file writes to the pipe of this capture.
+	Top bool
+	// PipeCapacity is the capacity of the capture pipe in bytes or zero if it's unknown on this platform.
+	PipeCapacity int
+	// Kind is the kind of the original output file.
+	Kind FileKind
+}
+
+// String returns a short human-readable description of the capture.
+func (info CaptureInfo) String() string {
+	names := make([]string, len(info.Targets))
+	for i, target := range info.Targets {
+		names[i] = target.Name
+	}
+
+	return fmt.Sprintf("capture #%d of %s created at %s", info.ID, strings.Join(names, ", "), info.CreatedAt)
+}
+
+// ActiveCaptures returns the descriptions of all the active captures in the order they were created.
+// It's intended for debugging misbehaving captures.
+func ActiveCaptures() []CaptureInfo {
+	captureLock.Lock()
+	defer captureLock.Unlock()
+
+	infos := make([]CaptureInfo, len(activeCaptures))
+	for i, c := range activeCaptures {
+		infos[i] = c.info()
+	}
+
+	return infos
+}
+
+func registerCapture(c *capture) {
+	lastCaptureID++
+	c.id = lastCaptureID
+	c.createdAt = callerOutsidePackage()
+	c.stack = debug.Stack()
+	c.leakHandler = leakHandler
+
+	activeCaptures = append(activeCaptures, c)
+	for _, outFile := range c.outFiles {
+		captureStacks[outFile] = append(captureStacks[outFile], c)
+	}
+}
+
+func unregisterCapture(c *capture) {
+	activeCaptures = removeCapture(activeCaptures, c)
+
+	for _, outFile := range c.outFiles {
+		stack := removeCapture(captureStacks[outFile], c)
+		if len(stack) == 0 {
+			delete(captureStacks, outFile)
+			forgetWriteDeadline(outFile)
+
+			continue
+		}
+
+		captureStacks[outFile] = stack
+	}
+}
+
+func removeCapture(captures []*capture, c *capture) []*capture {
+	for i := range captures {
+		if captures[i] == c {
+			return append(captures[:i:i], captures[i+1:]...)
+		}
+	}
+
+	return captures
+}
+
+func (c *capture) info() CaptureInfo {
+	info := CaptureInfo{
+		ID:        c.id,
+		CreatedAt: c.createdAt,
+		Stack:     string(c.stack),
+		Targets:   make([]TargetInfo, len(c.targets)),
+	}
+
+	for i, target := range c.targets {
+		stack := captureStacks[target.File]
+		depth := stackDepth(stack, c)
+
+		info.Targets[i] = TargetInfo{
+			Name:         target.Name,
+			File:         target.File,
+			Depth:        depth,
+			Top:          depth == len(stack)-1,
+			PipeCapacity: c.pipeCapacities[i],
+			Kind:         c.kinds[i],
+		}
+	}
+
+	return info
+}
+
+func stackDepth(stack []*capture, c *capture) int {
+	for depth := range stack {
+		if stack[depth] == c {
+			return depth
+		}
+	}
+
+	return -1
+}
+
+// captureAbove returns the capture stacked directly on top of the capture for the given output file or nil.
+func captureAbove(c *capture, outFile *os.File) *capture {
+	stack := captureStacks[outFile]
+	if depth := stackDepth(stack, c); depth >= 0 && depth < len(stack)-1 {
+		return stack[depth+1]
+	}
+
+	return nil
+}
+
+// callerOutsidePackage returns the "file:line" location of the first caller outside of this package
+// (the tests of the package are considered to be outside).
+func callerOutsidePackage() string {
+	const maxDepth = 32
+
+	pcs := make([]uintptr, maxDepth)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(1, pcs)])
+
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePath+".") || strings.HasSuffix(frame.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+
+		if !more {
+			return "unknown location"
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package flowmingo_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestActiveCaptures_DescribesStackedCaptures(t *testing.T) {
+	restore1 := flowmingo.CaptureStdoutAndStderr()
+	restore2 := flowmingo.Capture(os.Stderr)
+
+	infos := flowmingo.ActiveCaptures()
+	restore2(false)
+	restore1(false)
+
+	assertTrue(t, len(infos) >= 2, "Expected at least 2 active captures")
+
+	info1 := infos[len(infos)-2]
+	info2 := infos[len(infos)-1]
+
+	assertEqualInts(t, int(info1.ID)+1, int(info2.ID))
+	assertTrue(t, strings.HasSuffix(info1.CreatedAt, "registry_test.go:13"), "Unexpected location: "+info1.CreatedAt)
+	assertTrue(t, strings.Contains(info1.Stack, "TestActiveCaptures_DescribesStackedCaptures"), "Unexpected stack: "+info1.Stack)
+
+	assertEqualInts(t, 2, len(info1.Targets))
+	assertEqualStrings(t, flowmingo.StdoutName, info1.Targets[0].Name)
+	assertEqualFiles(t, os.Stdout, info1.Targets[0].File)
+	assertTrue(t, info1.Targets[0].Top, "Expected stdout of the first capture to be on top")
+	assertEqualStrings(t, flowmingo.StderrName, info1.Targets[1].Name)
+	assertTrue(t, !info1.Targets[1].Top, "Expected stderr of the first capture not to be on top")
+
+	assertEqualInts(t, 1, len(info2.Targets))
+	assertEqualInts(t, info1.Targets[1].Depth+1, info2.Targets[0].Depth)
+	assertTrue(t, info2.Targets[0].Top, "Expected stderr of the second capture to be on top")
+
+	assertEqualStrings(t, fmt.Sprintf("capture #%d of stdout, stderr created at %s", info1.ID, info1.CreatedAt), info1.String())
+
+	for _, info := range flowmingo.ActiveCaptures() {
+		if info.ID == info1.ID || info.ID == info2.ID {
+			t.Errorf("Expected %s to be removed from the active captures", info)
+		}
+	}
+}
+
+func TestActiveCaptures_UpdatesDepthsOnOutOfOrderRestore(t *testing.T) {
+	restore1 := flowmingo.Capture(os.Stdout)
+	restore2 := flowmingo.Capture(os.Stdout)
+
+	infos := flowmingo.ActiveCaptures()
+	depth := infos[len(infos)-1].Targets[0].Depth
+
+	restore1(false)
+
+	infos = flowmingo.ActiveCaptures()
+	info := infos[len(infos)-1]
+
+	restore2(false)
+
+	assertEqualInts(t, depth-1, info.Targets[0].Depth)
+	assertTrue(t, info.Targets[0].Top, "Expected the remaining capture to be on top")
+}
+
+func assertTrue(t *testing.T, condition bool, message string) {
+	t.Helper()
+
+	if !condition {
+		t.Error(message)
+	}
+}
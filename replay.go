@@ -0,0 +1,50 @@
+package flowmingo
+
+import (
+	"io"
+	"time"
+)
+
+// ReplayOptions configures Replay.
+type ReplayOptions struct {
+	// Speed is the pace of the replay relative to the pace the chunks were captured at.
+	// Zero means the chunks are written immediately one after another,
+	// 1 means they are written at the original pace (using the recorded times of the chunks),
+	// and N means they are written N times faster than the original pace.
+	Speed float64
+}
+
+// Replay writes the captured chunks to the writers in the order they were captured.
+//
+// The writers are keyed by the names of the chunks (see Target), so the captured output
+// can be written to any set of writers, not only to the original output files.
+// The chunks with the names that have no writers are skipped.
+//
+// Replay returns the first error returned by a writer.
+func Replay(chunks []ChunkFromFile, writers map[string]io.Writer, opts ReplayOptions) error {
+	var (
+		prevTime time.Time
+		cache    blockCache
+	)
+
+	for _, chunk := range chunks {
+		if opts.Speed > 0 && !prevTime.IsZero() && chunk.Time.After(prevTime) {
+			time.Sleep(time.Duration(float64(chunk.Time.Sub(prevTime)) / opts.Speed))
+		}
+
+		if !chunk.Time.IsZero() {
+			prevTime = chunk.Time
+		}
+
+		writer, ok := writers[chunk.Name]
+		if !ok {
+			continue
+		}
+
+		if _, err := writer.Write(cache.bytes(chunk)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package flowmingo_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestReplay_WritesChunksToWritersByName(t *testing.T) {
+	restore := flowmingo.CaptureStdoutAndStderr()
+	_, _ = os.Stdout.WriteString("out1")
+	time.Sleep(10 * time.Millisecond)
+	_, _ = os.Stderr.WriteString("err")
+	time.Sleep(10 * time.Millisecond)
+	_, _ = os.Stdout.WriteString("out2")
+	result := restore(false)
+
+	var outBuf, combinedBuf bytes.Buffer
+
+	err := flowmingo.Replay(result, map[string]io.Writer{flowmingo.StdoutName: &outBuf}, flowmingo.ReplayOptions{})
+	assertNoError(t, err)
+	assertEqualStrings(t, "out1out2", outBuf.String())
+
+	err = flowmingo.Replay(result, map[string]io.Writer{
+		flowmingo.StdoutName: &combinedBuf,
+		flowmingo.StderrName: &combinedBuf,
+	}, flowmingo.ReplayOptions{})
+	assertNoError(t, err)
+	assertEqualStrings(t, "out1errout2", combinedBuf.String())
+}
+
+func TestReplay_KeepsOriginalPace(t *testing.T) {
+	start := time.Now()
+	chunks := []flowmingo.ChunkFromFile{
+		{Chunk: []byte("a"), Name: "x", Time: start},
+		{Chunk: []byte("b"), Name: "x", Time: start.Add(100 * time.Millisecond)},
+	}
+
+	var buf bytes.Buffer
+
+	replayStart := time.Now()
+	err := flowmingo.Replay(chunks, map[string]io.Writer{"x": &buf}, flowmingo.ReplayOptions{Speed: 1})
+	assertNoError(t, err)
+
+	if elapsed := time.Since(replayStart); elapsed < 100*time.Millisecond {
+		t.Errorf("Replay at the original pace took %s, expected at least 100ms", elapsed)
+	}
+
+	replayStart = time.Now()
+	err = flowmingo.Replay(chunks, map[string]io.Writer{"x": &buf}, flowmingo.ReplayOptions{Speed: 10})
+	assertNoError(t, err)
+
+	if elapsed := time.Since(replayStart); elapsed < 10*time.Millisecond || elapsed >= 100*time.Millisecond {
+		t.Errorf("Replay at 10x speed took %s, expected about 10ms", elapsed)
+	}
+
+	assertEqualStrings(t, "abab", buf.String())
+}
+
+type failingWriter struct{}
+
+var errWriteFailed = errors.New("write failed")
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errWriteFailed
+}
+
+func TestReplay_ReturnsWriteError(t *testing.T) {
+	chunks := []flowmingo.ChunkFromFile{{Chunk: []byte("a"), Name: "x"}}
+
+	err := flowmingo.Replay(chunks, map[string]io.Writer{"x": failingWriter{}}, flowmingo.ReplayOptions{})
+	if err != errWriteFailed {
+		t.Errorf("Expected the write error, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package flowmingo
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// Result is the output captured by Capture in the order it was captured.
+//
+// Result is a slice of ChunkFromFile, so it can be ranged over, indexed and
+// assigned to a []ChunkFromFile variable like the captured chunks always could.
+type Result []ChunkFromFile
+
+// Stdout returns the output captured from the target named StdoutName (os.Stdout by default).
+func (r Result) Stdout() string {
+	return string(r.ByName(StdoutName).Combined())
+}
+
+// Stderr returns the output captured from the target named StderrName (os.Stderr by default).
+func (r Result) Stderr() string {
+	return string(r.ByName(StderrName).Combined())
+}
+
+// BytesFor returns the output captured from the given output file.
+func (r Result) BytesFor(outFile *os.File) []byte {
+	return r.Filter(func(chunk ChunkFromFile) bool { return chunk.OutFile == outFile }).Combined()
+}
+
+// StringFor returns the output captured from the given output file as a string.
+func (r Result) StringFor(outFile *os.File) string {
+	return string(r.BytesFor(outFile))
+}
+
+// Lines returns the output captured from the given output file split into lines.
+// The line terminators are not included. The last line is returned even if it is not terminated.
+func (r Result) Lines(outFile *os.File) []string {
+	output := r.StringFor(outFile)
+	if output == "" {
+		return nil
+	}
+
+	lines := make([]string, 0, strings.Count(output, "\n")+1)
+
+	for output != "" {
+		i := strings.IndexByte(output, '\n')
+		if i < 0 {
+			lines = append(lines, output)
+
+			break
+		}
+
+		lines = append(lines, output[:i])
+		output = output[i+1:]
+	}
+
+	return lines
+}
+
+// Combined returns the output captured from all the output files concatenated in the order it was captured.
+func (r Result) Combined() []byte {
+	size := 0
+	for _, chunk := range r {
+		size += chunk.Len()
+	}
+
+	var cache blockCache
+
+	combined := make([]byte, 0, size)
+	for _, chunk := range r {
+		combined = append(combined, cache.bytes(chunk)...)
+	}
+
+	return combined
+}
+
+// Filter returns the chunks for which pred returns true keeping their order.
+func (r Result) Filter(pred func(chunk ChunkFromFile) bool) Result {
+	var filtered Result
+
+	for _, chunk := range r {
+		if pred(chunk) {
+			filtered = append(filtered, chunk)
+		}
+	}
+
+	return filtered
+}
+
+// ByName returns the chunks labeled with the given name keeping their order.
+func (r Result) ByName(name string) Result {
+	return ByName(r, name)
+}
+
+// WriteTo writes the output captured from all the output files to w in the order it was captured.
+// It implements io.WriterTo.
+func (r Result) WriteTo(w io.Writer) (int64, error) {
+	var (
+		written int64
+		cache   blockCache
+	)
+
+	for _, chunk := range r {
+		n, err := w.Write(cache.bytes(chunk))
+		written += int64(n)
+
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
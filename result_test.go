@@ -0,0 +1,67 @@
+package flowmingo_test
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestResult_PerFileAccessors(t *testing.T) {
+	restore := flowmingo.CaptureStdoutAndStderr()
+	_, _ = os.Stdout.WriteString("line 1\nline 2\n")
+	time.Sleep(10 * time.Millisecond)
+	_, _ = os.Stderr.WriteString("oops\n")
+	time.Sleep(10 * time.Millisecond)
+	_, _ = os.Stdout.WriteString("line 3")
+	result := restore(false)
+
+	assertEqualStrings(t, "line 1\nline 2\nline 3", result.Stdout())
+	assertEqualStrings(t, "oops\n", result.Stderr())
+	assertEqualStrings(t, "line 1\nline 2\nline 3", result.StringFor(os.Stdout))
+	assertEqualStrings(t, "oops\n", string(result.BytesFor(os.Stderr)))
+	assertEqualStrings(t, "line 1\nline 2\noops\nline 3", string(result.Combined()))
+
+	lines := result.Lines(os.Stdout)
+	assertEqualStrings(t, "line 1|line 2|line 3", strings.Join(lines, "|"))
+	assertEqualInts(t, 1, len(result.Lines(os.Stderr)))
+	assertEqualInts(t, 0, len(flowmingo.Result(nil).Lines(os.Stdout)))
+}
+
+func TestResult_FilterAndByName(t *testing.T) {
+	result := flowmingo.Result{
+		{Chunk: []byte("a"), Name: "x"},
+		{Chunk: []byte("bb"), Name: "y"},
+		{Chunk: []byte("cc"), Name: "x"},
+	}
+
+	long := result.Filter(func(chunk flowmingo.ChunkFromFile) bool { return len(chunk.Chunk) > 1 })
+	assertEqualStrings(t, "bbcc", string(long.Combined()))
+	assertEqualStrings(t, "acc", string(result.ByName("x").Combined()))
+}
+
+func TestResult_WriteTo(t *testing.T) {
+	result := flowmingo.Result{
+		{Chunk: []byte("ab"), Name: "x"},
+		{Chunk: []byte("c"), Name: "y"},
+	}
+
+	var buf bytes.Buffer
+
+	written, err := result.WriteTo(&buf)
+	assertNoError(t, err)
+	assertEqualInts(t, 3, int(written))
+	assertEqualStrings(t, "abc", buf.String())
+}
+
+func TestResult_IsAssignableToChunksSlice(t *testing.T) {
+	restore := flowmingo.Capture(os.Stdout)
+	_, _ = os.Stdout.WriteString("out")
+
+	var chunks []flowmingo.ChunkFromFile = restore(false)
+
+	assertEqualInts(t, 1, len(chunks))
+}
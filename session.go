@@ -0,0 +1,308 @@
+package flowmingo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// DefaultSessionTimeout is the default timeout of the steps of a Session.
+const DefaultSessionTimeout = 10 * time.Second
+
+// everything matches the whole rest of the output.
+var everything = regexp.MustCompile(`(?s)\A.*`)
+
+// Session scripts an interactive program: it sends the input to the program and expects its output
+// step by step like the "expect" tool does. The program is either a function run in-process
+// with the injected STDIN and captured STDOUT and STDERR (see NewFuncSession) or a command (see NewCmdSession).
+//
+// The steps are recorded in the transcript with the input prefixed by "> ", the output prefixed by "< ",
+// and the error output (recorded at the start of every step and on failures) prefixed by "<2 ".
+// Once a step fails, the next steps do nothing and return the same *SessionError, so a script
+// can check the error once at the end. The session must be closed with Close.
+type Session struct {
+	// Timeout is the timeout of the steps without an explicit timeout. It's DefaultSessionTimeout by default.
+	Timeout time.Duration
+
+	input        io.WriteCloser
+	restoreInput func()
+	capturing    *Capturing
+	finished     chan struct{} // closed when the program finishes
+	kill         func()        // nil if the program can't be killed
+	exitErr      error         // the error the program finished with
+
+	transcript bytes.Buffer
+	steps      int
+	err        *SessionError
+	closed     bool
+	result     Result
+}
+
+// SessionError is returned by the steps of a Session when the output doesn't match the expectation.
+type SessionError struct {
+	// Step is the number of the failed step starting from 1.
+	Step int
+	// Err is the reason of the failure.
+	Err error
+	// Transcript is the transcript of the session up to the failure.
+	Transcript string
+}
+
+func (e *SessionError) Error() string {
+	return fmt.Sprintf("session step #%d failed: %s\n\ntranscript:\n%s", e.Step, e.Err, e.Transcript)
+}
+
+// NewFuncSession injects STDIN (see InjectStdin), captures STDOUT and STDERR, and runs fn in a new goroutine.
+// The session ends when fn returns. If fn panics, the panic is recorded in the transcript
+// and reported as a *PanicError by ExpectEOF.
+func NewFuncSession(fn func()) *Session {
+	input := InjectStdin()
+	s := newSession(input, Start(Options{}, Target{File: os.Stdout}, Target{File: os.Stderr}))
+	s.restoreInput = input.Restore
+
+	go func() {
+		defer close(s.finished)
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				s.exitErr = &PanicError{Value: recovered, Stack: debug.Stack()}
+			}
+		}()
+
+		fn()
+	}()
+
+	return s
+}
+
+// NewCmdSession starts the command with its STDIN connected to the session and its output captured
+// (see StartCmd). The session ends when the command exits. cmd.Stdin, cmd.Stdout and cmd.Stderr must not be set.
+func NewCmdSession(cmd *exec.Cmd) (*Session, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	capturing := StartCmd(Options{}, cmd)
+
+	if err = cmd.Start(); err != nil {
+		_ = stdin.Close()
+		capturing.Restore(false)
+
+		return nil, err
+	}
+
+	s := newSession(stdin, capturing)
+	s.kill = func() { _ = cmd.Process.Kill() }
+
+	go func() {
+		s.exitErr = cmd.Wait()
+		close(s.finished)
+	}()
+
+	return s, nil
+}
+
+func newSession(input io.WriteCloser, capturing *Capturing) *Session {
+	return &Session{Timeout: DefaultSessionTimeout, input: input, capturing: capturing, finished: make(chan struct{})}
+}
+
+// Send sends the input to the program. It fails if the program doesn't read the input within the timeout
+// of the session while the input pipe is full. The input is closed then, so the program gets EOF
+// after the part of the input it has already got.
+func (s *Session) Send(input string) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.steps++
+	s.recordStderr()
+	s.record("> ", input)
+
+	written := make(chan error, 1)
+
+	go func() {
+		_, err := io.WriteString(s.input, input)
+		written <- err
+	}()
+
+	timer := time.NewTimer(s.Timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-written:
+		if err != nil {
+			return s.fail(err)
+		}
+	case <-timer.C:
+		// unblock the write
+		_ = s.input.Close()
+
+		return s.fail(fmt.Errorf("expected the program to read the input within %s", s.Timeout))
+	}
+
+	return nil
+}
+
+// SendEOF closes the input of the program, so the program gets EOF once it reads all the input sent before.
+func (s *Session) SendEOF() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.steps++
+	s.recordStderr()
+	s.record("> ", "EOF\n")
+
+	if err := s.input.Close(); err != nil {
+		return s.fail(err)
+	}
+
+	return nil
+}
+
+// Expect waits for STDOUT of the program to match the regular expression within the timeout of the session.
+// The output is consumed up to the end of the match (see (*Capturing).WaitFor).
+func (s *Session) Expect(re *regexp.Regexp) error {
+	return s.ExpectWithin(re, s.Timeout)
+}
+
+// ExpectWithin works like Expect with the given timeout.
+func (s *Session) ExpectWithin(re *regexp.Regexp, timeout time.Duration) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.steps++
+	s.recordStderr()
+
+	output, err := s.capturing.capture.waitFor(StdoutName, re, timeout)
+	if err != nil {
+		if timeoutErr, ok := err.(*WaitTimeoutError); ok {
+			s.record("< ", timeoutErr.Output)
+			err = fmt.Errorf("expected the output to match %q within %s", re, timeout)
+		}
+
+		return s.fail(err)
+	}
+
+	s.record("< ", output)
+
+	return nil
+}
+
+// ExpectEOF waits for the program to finish within the timeout of the session and closes the session.
+// The rest of the output is consumed. ExpectEOF fails if the function panics (with *PanicError as Err)
+// or if the command fails, e.g. exits with a non-zero status (with *exec.ExitError as Err).
+func (s *Session) ExpectEOF() error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.steps++
+	s.recordStderr()
+
+	timer := time.NewTimer(s.Timeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.finished:
+	case <-timer.C:
+		return s.fail(fmt.Errorf("expected the program to finish within %s", s.Timeout))
+	}
+
+	s.Close()
+
+	// a remainder like the newline after the last match is not worth a line of the transcript
+	if output, _ := s.capturing.capture.waitFor(StdoutName, everything, 0); strings.TrimSpace(output) != "" {
+		s.record("< ", output)
+	}
+
+	if s.exitErr != nil {
+		return s.fail(s.exitErr)
+	}
+
+	s.recordStderr()
+
+	s.record("", "EOF\n")
+
+	return nil
+}
+
+// Err returns the error of the failed step or nil.
+func (s *Session) Err() error {
+	if s.err == nil {
+		return nil
+	}
+
+	return s.err
+}
+
+// Transcript returns the transcript of the session.
+func (s *Session) Transcript() string {
+	return s.transcript.String()
+}
+
+// Close ends the session and returns all the captured output. It closes the input and waits for the program
+// to finish within the timeout of the session. A command still running is killed then, a function is left running
+// with the restored STDIN, STDOUT and STDERR. Close can be called more than once.
+func (s *Session) Close() Result {
+	if s.closed {
+		return s.result
+	}
+
+	s.closed = true
+	_ = s.input.Close()
+
+	timer := time.NewTimer(s.Timeout)
+	defer timer.Stop()
+
+	select {
+	case <-s.finished:
+	case <-timer.C:
+		if s.kill != nil {
+			s.kill()
+			<-s.finished
+		}
+	}
+
+	s.result = s.capturing.Restore(false)
+
+	if s.restoreInput != nil {
+		s.restoreInput()
+	}
+
+	return s.result
+}
+
+// record adds the text to the transcript prefixing every line of it.
+func (s *Session) record(prefix, text string) {
+	if text == "" {
+		return
+	}
+
+	for _, line := range strings.SplitAfter(strings.TrimSuffix(text, "\n"), "\n") {
+		s.transcript.WriteString(prefix + strings.TrimSuffix(line, "\n") + "\n")
+	}
+}
+
+// recordStderr adds the error output written since it was recorded last time to the transcript.
+func (s *Session) recordStderr() {
+	if output, _ := s.capturing.capture.waitFor(StderrName, everything, 0); strings.TrimSpace(output) != "" {
+		s.record("<2 ", output)
+	}
+}
+
+func (s *Session) fail(err error) error {
+	s.recordStderr()
+	s.record("!! ", err.Error())
+
+	s.err = &SessionError{Step: s.steps, Err: err, Transcript: s.transcript.String()}
+
+	return s.err
+}
@@ -0,0 +1,98 @@
+package flowmingo_test
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func greeter() {
+	reader := bufio.NewReader(os.Stdin)
+
+	fmt.Print("Name: ")
+
+	name, _ := reader.ReadString('\n')
+	fmt.Printf("Hello, %s", name)
+}
+
+func TestFuncSession(t *testing.T) {
+	session := flowmingo.NewFuncSession(greeter)
+	defer session.Close()
+
+	_ = session.Expect(regexp.MustCompile(`Name: $`))
+	_ = session.Send("gopher\n")
+	_ = session.Expect(regexp.MustCompile(`Hello, \w+`))
+	assertNoError(t, session.ExpectEOF())
+
+	assertEqualStrings(t, "< Name: \n> gopher\n< Hello, gopher\nEOF\n", session.Transcript())
+	assertEqualStrings(t, "Name: Hello, gopher\n", session.Close().Stdout())
+}
+
+func TestFuncSession_FailsWithTranscript(t *testing.T) {
+	session := flowmingo.NewFuncSession(greeter)
+	session.Timeout = 100 * time.Millisecond
+
+	_ = session.Expect(regexp.MustCompile(`Name: $`))
+	_ = session.Send("gopher\n")
+	_ = session.Expect(regexp.MustCompile(`Goodbye`))
+	err := session.ExpectEOF()
+
+	session.Close()
+
+	sessionErr, ok := err.(*flowmingo.SessionError)
+	assertTrue(t, ok, "Expected a *SessionError")
+	assertEqualInts(t, 3, sessionErr.Step)
+	assertTrue(t, strings.HasSuffix(sessionErr.Transcript,
+		"> gopher\n< Hello, gopher\n!! expected the output to match \"Goodbye\" within 100ms\n"),
+		"Unexpected transcript:\n"+sessionErr.Transcript)
+	assertTrue(t, session.Err() == err, "Expected the error to be kept")
+}
+
+func TestFuncSession_ReportsPanic(t *testing.T) {
+	session := flowmingo.NewFuncSession(func() { panic("boom") })
+	defer session.Close()
+
+	err := session.ExpectEOF()
+
+	sessionErr, ok := err.(*flowmingo.SessionError)
+	assertTrue(t, ok, "Expected a *SessionError")
+
+	panicErr, ok := sessionErr.Err.(*flowmingo.PanicError)
+	assertTrue(t, ok, "Expected a *PanicError")
+	assertEqualStrings(t, "boom", fmt.Sprint(panicErr.Value))
+}
+
+func TestFuncSession_SendTimeoutClosesInput(t *testing.T) {
+	start := make(chan bool)
+	read := make(chan int, 1)
+
+	session := flowmingo.NewFuncSession(func() {
+		<-start
+
+		input, _ := ioutil.ReadAll(os.Stdin)
+		read <- len(input)
+	})
+	session.Timeout = 100 * time.Millisecond
+
+	defer session.Close()
+
+	err := session.Send(strings.Repeat("x", 1<<20))
+	assertTrue(t, err != nil, "Expected the input not to be read in time")
+
+	// the function gets EOF after the part of the input already in the pipe
+	close(start)
+
+	select {
+	case n := <-read:
+		assertTrue(t, n < 1<<20, "Expected only a part of the input to be read")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the input to be closed")
+	}
+}
@@ -0,0 +1,254 @@
+package flowmingo
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink receives the captured chunks instead of the capture keeping them in memory (see Options.Sink).
+type Sink interface {
+	// WriteChunk is called by the capture for every captured chunk in the order they were captured.
+	// It must not retain the chunk data after returning.
+	WriteChunk(chunk ChunkFromFile)
+	// Flush is called when the capture is restored, after the last chunk.
+	Flush()
+}
+
+// RotationOptions configures the rotation of a RotatingFileSink.
+// The zero value never rotates the file.
+type RotationOptions struct {
+	// MaxSize is the size in bytes the file is rotated at. A chunk is never split between files,
+	// so a file gets larger than MaxSize if a single chunk is larger. Zero disables rotating by size.
+	MaxSize int64
+	// Interval is the age the file is rotated at. The file is rotated on the first chunk coming
+	// after it got that old. Zero disables rotating by time.
+	Interval time.Duration
+	// Compress makes the sink compress the rotated files with gzip (adding the ".gz" extension).
+	Compress bool
+	// MaxBackups is the number of rotated files to keep. The oldest ones are removed on rotation.
+	// Zero keeps all the rotated files.
+	MaxBackups int
+}
+
+// RotatingFileSink is a Sink writing the captured output to a file that is rotated by size or by time.
+// The rotated files are named after the file with the time of the rotation appended,
+// like "out.log.20240102-150405.000000000". It's safe for use by several captures at once.
+//
+// The errors of writing, rotating and removing the files don't stop capturing, the first one is reported by Err.
+type RotatingFileSink struct {
+	lock     sync.Mutex
+	path     string
+	opts     RotationOptions
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	err      error
+}
+
+const rotatedFileTimeFormat = "20060102-150405.000000000"
+
+// NewRotatingFileSink opens (or creates) the file at the path for appending and returns the sink writing to it.
+func NewRotatingFileSink(path string, opts RotationOptions) (*RotatingFileSink, error) {
+	s := &RotatingFileSink{path: path, opts: opts}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *RotatingFileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return err
+	}
+
+	s.file, s.size, s.openedAt = file, info.Size(), time.Now()
+
+	return nil
+}
+
+// WriteChunk writes the chunk data to the file rotating the file before if needed.
+func (s *RotatingFileSink) WriteChunk(chunk ChunkFromFile) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file == nil {
+		return // closed or failed to reopen after rotating
+	}
+
+	if s.needsRotation(int64(len(chunk.Chunk))) {
+		s.rotate()
+
+		if s.file == nil {
+			return
+		}
+	}
+
+	n, err := s.file.Write(chunk.Chunk)
+	s.size += int64(n)
+	s.setErr(err)
+}
+
+func (s *RotatingFileSink) needsRotation(size int64) bool {
+	if s.size == 0 {
+		return false
+	}
+
+	return s.opts.MaxSize > 0 && s.size+size > s.opts.MaxSize ||
+		s.opts.Interval > 0 && time.Since(s.openedAt) >= s.opts.Interval
+}
+
+func (s *RotatingFileSink) rotate() {
+	s.setErr(s.file.Close())
+	s.file = nil
+
+	rotatedPath := s.path + "." + time.Now().Format(rotatedFileTimeFormat)
+	if err := os.Rename(s.path, rotatedPath); err != nil {
+		s.setErr(err)
+	} else if s.opts.Compress {
+		s.setErr(compressFile(rotatedPath))
+	}
+
+	s.removeOldBackups()
+	s.setErr(s.open())
+}
+
+func (s *RotatingFileSink) removeOldBackups() {
+	if s.opts.MaxBackups <= 0 {
+		return
+	}
+
+	backups, err := s.backups()
+	if err != nil {
+		s.setErr(err)
+
+		return
+	}
+
+	// the names of the rotated files sort in the order of the rotation
+	sort.Strings(backups)
+
+	for len(backups) > s.opts.MaxBackups {
+		s.setErr(os.Remove(backups[0]))
+		backups = backups[1:]
+	}
+}
+
+// backups returns the paths of the rotated files of the sink, skipping the other files in the directory
+// even if their names start with the name of the sink file.
+func (s *RotatingFileSink) backups() ([]string, error) {
+	dir := filepath.Dir(s.path)
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(s.path) + "."
+
+	var backups []string
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		rotatedAt := strings.TrimSuffix(name[len(prefix):], ".gz")
+		if _, err := time.Parse(rotatedFileTimeFormat, rotatedAt); err != nil {
+			continue
+		}
+
+		backups = append(backups, filepath.Join(dir, name))
+	}
+
+	return backups, nil
+}
+
+// compressFile replaces the file with its gzip-compressed version adding the ".gz" extension.
+func compressFile(path string) error {
+	in, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	gzipWriter := gzip.NewWriter(out)
+
+	_, err = io.Copy(gzipWriter, in)
+	if closeErr := gzipWriter.Close(); err == nil {
+		err = closeErr
+	}
+
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(path + ".gz")
+
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// Flush commits the written output to the stable storage.
+func (s *RotatingFileSink) Flush() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file != nil {
+		s.setErr(s.file.Sync())
+	}
+}
+
+// Close closes the file. The chunks written after closing are dropped.
+func (s *RotatingFileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+
+	return err
+}
+
+// Err returns the first error the sink encountered.
+func (s *RotatingFileSink) Err() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.err
+}
+
+func (s *RotatingFileSink) setErr(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
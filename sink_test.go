@@ -0,0 +1,135 @@
+package flowmingo_test
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestRotatingFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flowmingo")
+	assertNoError(t, err)
+
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "out.log")
+	sink, err := flowmingo.NewRotatingFileSink(path, flowmingo.RotationOptions{MaxSize: 10, Compress: true, MaxBackups: 2})
+	assertNoError(t, err)
+
+	capturing := flowmingo.Start(flowmingo.Options{Sink: sink}, flowmingo.Target{File: os.Stdout})
+
+	stdout := capturing.Writer(flowmingo.StdoutName)
+	for _, chunk := range []string{"111111", "222222", "333333", "444444", "55"} {
+		_, _ = stdout.Write([]byte(chunk))
+	}
+
+	result := capturing.Restore(false)
+
+	assertNoError(t, sink.Close())
+	assertNoError(t, sink.Err())
+	assertEqualInts(t, 0, len(result))
+
+	current, err := ioutil.ReadFile(path)
+	assertNoError(t, err)
+	assertEqualStrings(t, "44444455", string(current))
+
+	backups, err := filepath.Glob(path + ".*.gz")
+	assertNoError(t, err)
+	sort.Strings(backups)
+	assertEqualInts(t, 2, len(backups))
+	assertEqualStrings(t, "222222", readGzipFile(t, backups[0]))
+	assertEqualStrings(t, "333333", readGzipFile(t, backups[1]))
+}
+
+func TestRotatingFileSink_RotatesByTime(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flowmingo")
+	assertNoError(t, err)
+
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "out.log")
+	sink, err := flowmingo.NewRotatingFileSink(path, flowmingo.RotationOptions{Interval: time.Millisecond})
+	assertNoError(t, err)
+
+	sink.WriteChunk(flowmingo.ChunkFromFile{Chunk: []byte("old")})
+	time.Sleep(2 * time.Millisecond)
+	sink.WriteChunk(flowmingo.ChunkFromFile{Chunk: []byte("new")})
+	assertNoError(t, sink.Close())
+
+	current, err := ioutil.ReadFile(path)
+	assertNoError(t, err)
+	assertEqualStrings(t, "new", string(current))
+
+	backups, err := filepath.Glob(path + ".*")
+	assertNoError(t, err)
+	assertEqualInts(t, 1, len(backups))
+}
+
+func TestRotatingFileSink_KeepsUnrelatedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flowmingo")
+	assertNoError(t, err)
+
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "agent[1].log")
+	for _, unrelated := range []string{path + ".1", path + ".stderr"} {
+		assertNoError(t, ioutil.WriteFile(unrelated, []byte("unrelated"), 0600))
+	}
+
+	sink, err := flowmingo.NewRotatingFileSink(path, flowmingo.RotationOptions{MaxSize: 3, MaxBackups: 1})
+	assertNoError(t, err)
+
+	for _, chunk := range []string{"111", "222", "333"} {
+		sink.WriteChunk(flowmingo.ChunkFromFile{Chunk: []byte(chunk)})
+	}
+
+	assertNoError(t, sink.Close())
+	assertNoError(t, sink.Err())
+
+	entries, err := ioutil.ReadDir(dir)
+	assertNoError(t, err)
+	assertEqualInts(t, 4, len(entries))
+
+	for _, unrelated := range []string{path + ".1", path + ".stderr"} {
+		content, err := ioutil.ReadFile(unrelated)
+		assertNoError(t, err)
+		assertEqualStrings(t, "unrelated", string(content))
+	}
+
+	var backups []string
+
+	for _, entry := range entries {
+		if name := filepath.Join(dir, entry.Name()); name != path && name != path+".1" && name != path+".stderr" {
+			backups = append(backups, name)
+		}
+	}
+
+	assertEqualInts(t, 1, len(backups))
+
+	content, err := ioutil.ReadFile(backups[0])
+	assertNoError(t, err)
+	assertEqualStrings(t, "222", string(content))
+}
+
+func readGzipFile(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	assertNoError(t, err)
+
+	defer func() { _ = file.Close() }()
+
+	reader, err := gzip.NewReader(file)
+	assertNoError(t, err)
+
+	data, err := ioutil.ReadAll(reader)
+	assertNoError(t, err)
+
+	return string(data)
+}
@@ -0,0 +1,63 @@
+package flowmingo
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the runtime statistics of a capture collected when Options.CollectStats is set.
+type Stats struct {
+	// Bytes is the number of bytes captured so far.
+	Bytes int64
+	// Chunks is the number of chunks captured so far.
+	Chunks int64
+	// MaxPipeBacklog is the maximum number of bytes observed waiting in a capture pipe at the moment of reading.
+	// When it reaches the capacity of the pipe (see TargetInfo.PipeCapacity), the writers block.
+	// It's measured on Linux (with Go 1.12 or newer) only and is zero on other platforms and with Options.Ordered.
+	MaxPipeBacklog int64
+	// ReaderBlocked is the total time the pipe readers spent blocked waiting for the captured chunks
+	// to be collected. The pipes aren't drained during that time, so it's the time the writers may block for.
+	ReaderBlocked time.Duration
+}
+
+// captureStats are updated atomically by the pipe readers.
+// The 64-bit fields come first to be aligned on 32-bit platforms.
+type captureStats struct {
+	bytes          int64
+	chunks         int64
+	maxPipeBacklog int64
+	readerBlocked  int64
+}
+
+func (s *captureStats) addChunk(size int, pipeBacklog int, blocked time.Duration) {
+	atomic.AddInt64(&s.bytes, int64(size))
+	atomic.AddInt64(&s.chunks, 1)
+	atomic.AddInt64(&s.readerBlocked, int64(blocked))
+
+	for {
+		maxPipeBacklog := atomic.LoadInt64(&s.maxPipeBacklog)
+		if int64(pipeBacklog) <= maxPipeBacklog ||
+			atomic.CompareAndSwapInt64(&s.maxPipeBacklog, maxPipeBacklog, int64(pipeBacklog)) {
+			return
+		}
+	}
+}
+
+func (s *captureStats) snapshot() Stats {
+	if s == nil {
+		return Stats{}
+	}
+
+	return Stats{
+		Bytes:          atomic.LoadInt64(&s.bytes),
+		Chunks:         atomic.LoadInt64(&s.chunks),
+		MaxPipeBacklog: atomic.LoadInt64(&s.maxPipeBacklog),
+		ReaderBlocked:  time.Duration(atomic.LoadInt64(&s.readerBlocked)),
+	}
+}
+
+// Stats returns the runtime statistics of the capture collected so far.
+// They are zero unless Options.CollectStats is set. Stats can be called while capturing and after restoring.
+func (c *Capturing) Stats() Stats {
+	return c.capture.stats.snapshot()
+}
@@ -0,0 +1,70 @@
+package flowmingo_test
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestCapturing_Stats(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{CollectStats: true}, flowmingo.Target{File: os.Stdout})
+	_, _ = os.Stdout.WriteString("abc")
+	_, _ = os.Stdout.WriteString("defg")
+	result := capturing.Restore(false)
+
+	stats := capturing.Stats()
+
+	assertEqualInts(t, 7, int(stats.Bytes))
+	assertEqualInts(t, len(result), int(stats.Chunks))
+}
+
+func TestCapturing_StatsMeasurePipeBacklog(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the pipe backlog is measured on Linux only")
+	}
+
+	blocked := make(chan bool)
+	release := make(chan bool)
+
+	// the collector stops on the first chunk, so the pipe reader stops too, and the output piles up in the pipe
+	first := true
+	filter := func(flowmingo.ChunkFromFile) bool {
+		if first {
+			first = false
+			blocked <- true
+			<-release
+		}
+
+		return true
+	}
+
+	capturing := flowmingo.Start(flowmingo.Options{CollectStats: true, Filter: filter}, flowmingo.Target{File: os.Stdout})
+	_, _ = os.Stdout.WriteString("a")
+	<-blocked
+
+	chunk := strings.Repeat("x", 1000)
+	for i := 0; i < 3; i++ {
+		_, _ = os.Stdout.WriteString(chunk)
+	}
+
+	close(release)
+	capturing.Restore(false)
+
+	// at most the first 1000-byte write could be read before the reader stopped,
+	// so at least the other 2000 bytes were waiting in the pipe at the moment of the next read
+	stats := capturing.Stats()
+	assertTrue(t, stats.MaxPipeBacklog >= 2000 && stats.MaxPipeBacklog <= 3000,
+		fmt.Sprintf("Expected a backlog of 2000-3000 bytes, got %d", stats.MaxPipeBacklog))
+}
+
+func TestCapturing_StatsAreZeroUnlessCollected(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+	_, _ = os.Stdout.WriteString("abc")
+	capturing.Restore(false)
+
+	assertTrue(t, capturing.Stats() == flowmingo.Stats{}, "Expected zero stats")
+}
@@ -0,0 +1,115 @@
+package flowmingo
+
+import (
+	"fmt"
+	"os"
+)
+
+// Names given to the chunks captured from os.Stdout and os.Stderr by default.
+const (
+	StdoutName = "stdout"
+	StderrName = "stderr"
+)
+
+// Target is an output file to capture along with the name to label the chunks captured from it.
+//
+// The name lets the callers tell the captured outputs apart without comparing *os.File pointers,
+// which stops working once the file has been swapped, duplicated or wrapped.
+// If Name is empty, the default name is used: StdoutName for os.Stdout, StderrName for os.Stderr
+// and the name of the file (as returned by (*os.File).Name) for any other file.
+//
+// The explicit names of the targets of a capture must be unique, since the outputs are told apart by them.
+// The default names are made unique by adding "#2", "#3", etc. to the names taken by the previous targets,
+// e.g. the write ends of two pipes created by os.Pipe are named "|1" and "|1#2".
+type Target struct {
+	File *os.File
+	Name string
+}
+
+func defaultName(outFile *os.File) string {
+	switch outFile {
+	case os.Stdout:
+		return StdoutName
+	case os.Stderr:
+		return StderrName
+	default:
+		return outFile.Name()
+	}
+}
+
+func targetFiles(targets []Target) []*os.File {
+	outFiles := make([]*os.File, len(targets))
+	for i := range targets {
+		outFiles[i] = targets[i].File
+	}
+
+	return outFiles
+}
+
+func defaultTargets(outFiles []*os.File) []Target {
+	targets := make([]Target, len(outFiles))
+	for i, outFile := range outFiles {
+		targets[i] = Target{File: outFile}
+	}
+
+	return namedTargets(targets)
+}
+
+// namedTargets gives the default names to the targets without names, so the names don't clash.
+func namedTargets(targets []Target) []Target {
+	mustNotContainDuplicateNames(targets)
+
+	taken := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		taken[target.Name] = true
+	}
+
+	named := make([]Target, len(targets))
+	for i, target := range targets {
+		if target.Name == "" {
+			target.Name = uniqueName(defaultName(target.File), taken)
+			taken[target.Name] = true
+		}
+
+		named[i] = target
+	}
+
+	return named
+}
+
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+	for n := 2; taken[unique]; n++ {
+		unique = fmt.Sprintf("%s#%d", name, n)
+	}
+
+	return unique
+}
+
+func mustNotContainDuplicateNames(targets []Target) {
+	names := make(map[string]struct{}, len(targets))
+	for _, target := range targets {
+		if target.Name == "" {
+			continue
+		}
+
+		if _, ok := names[target.Name]; ok {
+			panic(fmt.Sprintf("target name %q is duplicated, give the targets unique names", target.Name))
+		}
+
+		names[target.Name] = struct{}{}
+	}
+}
+
+// ByName returns the chunks labeled with the given name keeping their order.
+func ByName(chunks []ChunkFromFile, name string) []ChunkFromFile {
+	var filtered []ChunkFromFile
+
+	for _, chunk := range chunks {
+		if chunk.Name == name {
+			filtered = append(filtered, chunk)
+		}
+	}
+
+	return filtered
+}
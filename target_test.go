@@ -0,0 +1,115 @@
+package flowmingo_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestCapture_LabelsChunksWithDefaultNames(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	assertNoError(t, err)
+
+	defer func() { _ = reader.Close() }()
+	defer func() { _ = writer.Close() }()
+
+	restore := flowmingo.Capture(os.Stdout, writer)
+	_, _ = os.Stdout.WriteString("out")
+	chunks := restore(false)
+
+	assertEqualInts(t, 1, len(chunks))
+	assertEqualStrings(t, flowmingo.StdoutName, chunks[0].Name)
+
+	restore = flowmingo.Capture(os.Stderr, writer)
+	_, _ = writer.WriteString("custom")
+	chunks = restore(false)
+
+	assertEqualInts(t, 1, len(chunks))
+	assertEqualStrings(t, writer.Name(), chunks[0].Name)
+}
+
+func TestCaptureTargets_LabelsChunksWithGivenNames(t *testing.T) {
+	restore := flowmingo.CaptureTargets(
+		flowmingo.Target{File: os.Stdout, Name: "app-log"},
+		flowmingo.Target{File: os.Stderr},
+	)
+	_, _ = os.Stdout.WriteString("out")
+	chunks := restore(false)
+
+	assertEqualInts(t, 1, len(chunks))
+	assertEqualStrings(t, "app-log", chunks[0].Name)
+	assertEqualFiles(t, os.Stdout, chunks[0].OutFile)
+
+	restore = flowmingo.CaptureTargets(flowmingo.Target{File: os.Stderr})
+	_, _ = os.Stderr.WriteString("err")
+	chunks = restore(false)
+
+	assertEqualInts(t, 1, len(chunks))
+	assertEqualStrings(t, flowmingo.StderrName, chunks[0].Name)
+}
+
+func TestCaptureTargets_Nil(t *testing.T) {
+	assertPanics(t, func() { flowmingo.CaptureTargets(flowmingo.Target{Name: "nothing"}) })
+}
+
+func TestCaptureTargets_Duplicates(t *testing.T) {
+	assertPanics(t, func() {
+		flowmingo.CaptureTargets(flowmingo.Target{File: os.Stdout, Name: "a"}, flowmingo.Target{File: os.Stdout, Name: "b"})
+	})
+}
+
+func TestCaptureTargets_DuplicateNames(t *testing.T) {
+	assertPanics(t, func() {
+		flowmingo.CaptureTargets(flowmingo.Target{File: os.Stdout, Name: "log"}, flowmingo.Target{File: os.Stderr, Name: "log"})
+	})
+}
+
+func TestCapture_MakesDefaultNamesUnique(t *testing.T) {
+	reader1, writer1, err := os.Pipe()
+	assertNoError(t, err)
+
+	defer func() { _ = reader1.Close() }()
+	defer func() { _ = writer1.Close() }()
+
+	reader2, writer2, err := os.Pipe()
+	assertNoError(t, err)
+
+	defer func() { _ = reader2.Close() }()
+	defer func() { _ = writer2.Close() }()
+
+	restore := flowmingo.Capture(writer1, writer2)
+	_, _ = writer1.WriteString("1")
+	_, _ = writer2.WriteString("2")
+	result := flowmingo.Result(restore(false))
+
+	assertEqualStrings(t, "1", string(result.ByName(writer1.Name()).Combined()))
+	assertEqualStrings(t, "2", string(result.ByName(writer2.Name()+"#2").Combined()))
+
+	// the explicit names are kept, the default ones give way
+	restore = flowmingo.CaptureTargets(flowmingo.Target{File: os.Stdout, Name: flowmingo.StderrName}, flowmingo.Target{File: os.Stderr})
+	_, _ = os.Stderr.WriteString("err")
+	result = flowmingo.Result(restore(false))
+
+	assertEqualInts(t, 1, len(result))
+	assertEqualStrings(t, flowmingo.StderrName+"#2", result[0].Name)
+}
+
+func TestCaptureTargets_Empty(t *testing.T) {
+	assertPanics(t, func() { flowmingo.CaptureTargets() })
+}
+
+func TestByName(t *testing.T) {
+	chunks := []flowmingo.ChunkFromFile{
+		{Chunk: []byte("a"), Name: "x"},
+		{Chunk: []byte("b"), Name: "y"},
+		{Chunk: []byte("c"), Name: "x"},
+	}
+
+	filtered := flowmingo.ByName(chunks, "x")
+
+	assertEqualInts(t, 2, len(filtered))
+	assertEqualStrings(t, "a", string(filtered[0].Chunk))
+	assertEqualStrings(t, "c", string(filtered[1].Chunk))
+	assertEqualInts(t, 0, len(flowmingo.ByName(chunks, "z")))
+}
@@ -0,0 +1,99 @@
+package flowmingo
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// WaitTimeoutError is returned by (*Capturing).WaitFor when the output doesn't match in time.
+type WaitTimeoutError struct {
+	Name    string
+	Pattern string
+	// Output is the output of the target not consumed by the previous waits.
+	Output string
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("timed out waiting for the %s output to match %q, got:\n%s", e.Name, e.Pattern, e.Output)
+}
+
+// WaitFor waits until the output captured from the target with the given name matches the regular expression,
+// e.g. a prompt of an interactive program, and returns nil. The output is consumed up to the end of the match,
+// so the next call to WaitFor for the same target only sees the output after the match.
+//
+// WaitFor returns *WaitTimeoutError if the output doesn't match within the timeout or doesn't match
+// after the capture is restored. WaitFor sees the output after the redaction and the filter.
+// It panics if the capture has no target with the given name or if it uses a Sink.
+func (c *Capturing) WaitFor(name string, re *regexp.Regexp, timeout time.Duration) error {
+	_, err := c.capture.waitFor(name, re, timeout)
+
+	return err
+}
+
+// waitFor works like WaitFor and also returns the consumed output.
+func (c *capture) waitFor(name string, re *regexp.Regexp, timeout time.Duration) (string, error) {
+	if c.sink != nil {
+		panic("WaitFor can't be used with Sink")
+	}
+
+	mustHaveTarget(c, name)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		c.chunksFromPipesLock.Lock()
+
+		output := Result(c.chunksFromPipes).ByName(name).Combined()
+		consumed := c.waitCursors[name]
+
+		if loc := re.FindIndex(output[consumed:]); loc != nil {
+			c.waitCursors[name] = consumed + loc[1]
+			c.chunksFromPipesLock.Unlock()
+
+			return string(output[consumed : consumed+loc[1]]), nil
+		}
+
+		if c.collected {
+			c.chunksFromPipesLock.Unlock()
+
+			return "", &WaitTimeoutError{Name: name, Pattern: re.String(), Output: string(output[consumed:])}
+		}
+
+		if c.chunksNotify == nil {
+			c.chunksNotify = make(chan struct{})
+		}
+
+		notify := c.chunksNotify
+		c.chunksFromPipesLock.Unlock()
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			c.chunksFromPipesLock.Lock()
+			output = Result(c.chunksFromPipes).ByName(name).Combined()
+			c.chunksFromPipesLock.Unlock()
+
+			return "", &WaitTimeoutError{Name: name, Pattern: re.String(), Output: string(output[consumed:])}
+		}
+	}
+}
+
+// notifyChunks wakes up the waiters for the output. It must be called with chunksFromPipesLock locked.
+func (c *capture) notifyChunks() {
+	if c.chunksNotify != nil {
+		close(c.chunksNotify)
+		c.chunksNotify = nil
+	}
+}
+
+func mustHaveTarget(c *capture, name string) {
+	for _, target := range c.targets {
+		if target.Name == name {
+			return
+		}
+	}
+
+	panic(fmt.Sprintf("capture #%d has no target named %q", c.id, name))
+}
@@ -0,0 +1,53 @@
+package flowmingo
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// targetWriter writes to the capture bypassing the pipe.
+type targetWriter struct {
+	capture *capture
+	target  Target
+}
+
+// Writer returns a writer that writes right to the capture as if the output was written to the target
+// with the given name, but bypassing the pipe. The chunks written through it keep the boundaries of the writes.
+//
+// Since the pipes are drained asynchronously, the chunks written through the writer may get ahead of
+// the output written to the file right before them. The writer returns io.ErrClosedPipe after restoring.
+// Writer panics if the capture has no target with the given name.
+func (c *Capturing) Writer(name string) io.Writer {
+	for _, target := range c.capture.targets {
+		if target.Name == name {
+			return &targetWriter{capture: c.capture, target: target}
+		}
+	}
+
+	panic(fmt.Sprintf("capture #%d has no target named %q", c.capture.id, name))
+}
+
+func (w *targetWriter) Write(p []byte) (int, error) {
+	chunk := ChunkFromFile{
+		Chunk:   append([]byte(nil), p...),
+		OutFile: w.target.File,
+		Name:    w.target.Name,
+		Time:    time.Now(),
+	}
+
+	w.capture.writersLock.RLock()
+	defer w.capture.writersLock.RUnlock()
+
+	if w.capture.writersClosed {
+		return 0, io.ErrClosedPipe
+	}
+
+	w.capture.outC <- chunk
+
+	if w.capture.stats != nil {
+		w.capture.stats.addChunk(len(p), 0, 0)
+	}
+
+	return len(p), nil
+}
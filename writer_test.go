@@ -0,0 +1,36 @@
+package flowmingo_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/zenovich/flowmingo"
+)
+
+func TestCapturing_Writer(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+	_, _ = fmt.Fprint(capturing.Writer(flowmingo.StdoutName), "abc")
+	result := capturing.Restore(false)
+
+	assertEqualInts(t, 1, len(result))
+	assertEqualStrings(t, "abc", string(result[0].Chunk))
+	assertEqualFiles(t, os.Stdout, result[0].OutFile)
+}
+
+func TestCapturing_WriterFailsAfterRestore(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+	writer := capturing.Writer(flowmingo.StdoutName)
+	capturing.Restore(false)
+
+	_, err := writer.Write([]byte("abc"))
+	assertTrue(t, err == io.ErrClosedPipe, "Expected io.ErrClosedPipe")
+}
+
+func TestCapturing_WriterPanicsOnUnknownName(t *testing.T) {
+	capturing := flowmingo.Start(flowmingo.Options{}, flowmingo.Target{File: os.Stdout})
+	defer capturing.Restore(false)
+
+	assertPanics(t, func() { capturing.Writer("unknown") })
+}